@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/sunny0826/kubectl-pod-lens/pkg/plugin"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	configFlag string
+	// configErr is kept by initConfig, which cannot fail, and reported by
+	// the command that runs.
+	configErr error
+	// configContext is the kubeconfig context whose overrides were applied.
+	configContext string
+)
+
+var themes = []string{"default", "mono"}
+
+// settings are the keys a config file can set, globally or per context.
+var settings = []string{"output", "resources", "label-keys", "theme", "redact"}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".config", "pod-lens", "config.yaml")
+	}
+	return filepath.Join(home, ".config", "pod-lens", "config.yaml")
+}
+
+func configPath() string {
+	if configFlag != "" {
+		return configFlag
+	}
+	return defaultConfigPath()
+}
+
+func initConfig() {
+	viper.SetEnvPrefix("POD_LENS")
+	viper.AutomaticEnv()
+
+	viper.SetConfigFile(configPath())
+	viper.SetConfigType("yaml")
+	if err := viper.ReadInConfig(); err != nil {
+		// A missing default config file is fine, a missing --config is not.
+		if !os.IsNotExist(errors.Cause(err)) || configFlag != "" {
+			configErr = errors.Wrapf(err, "failed to read config file %s", configPath())
+		}
+		return
+	}
+	configErr = applyContextOverrides()
+}
+
+// applyContextOverrides merges the `contexts.<name>` section for the current
+// kubeconfig context over the global settings. Flags and env vars still win.
+func applyContextOverrides() error {
+	name := *KubernetesConfigFlags.Context
+	if name == "" {
+		raw, err := KubernetesConfigFlags.ToRawKubeConfigLoader().RawConfig()
+		if err != nil {
+			return nil
+		}
+		name = raw.CurrentContext
+	}
+	if name == "" {
+		return nil
+	}
+	// viper lower-cases keys, so context names are matched case-insensitively.
+	override, ok := viper.GetStringMap("contexts")[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	values, ok := override.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("config for context %q must be a map", name)
+	}
+	for key := range values {
+		if !isSetting(key) {
+			return errors.Errorf("unknown setting %q for context %q, expected any of %s", key, name, strings.Join(settings, ", "))
+		}
+	}
+	configContext = name
+	return viper.MergeConfigMap(values)
+}
+
+// applyConfig reports config errors, validates the settings and applies the
+// color theme.
+func applyConfig() error {
+	if configErr != nil {
+		return configErr
+	}
+	switch theme := viper.GetString("theme"); theme {
+	case "", "default":
+	case "mono":
+		cfmt.DisableColors()
+		pterm.DisableColor()
+	default:
+		return errors.Errorf("unknown theme %q, expected one of %s", theme, strings.Join(themes, ", "))
+	}
+	return nil
+}
+
+func isSetting(key string) bool {
+	for _, s := range settings {
+		if s == key {
+			return true
+		}
+	}
+	return false
+}
+
+// listSetting reads a list setting; values from env vars are comma separated.
+func listSetting(key string) []string {
+	var values []string
+	for _, v := range viper.GetStringSlice(key) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func effectiveConfig() map[string]interface{} {
+	labelKeys := listSetting("label-keys")
+	if len(labelKeys) == 0 {
+		labelKeys = plugin.DefaultLabelKeys
+	}
+	resources := listSetting("resources")
+	if len(resources) == 0 {
+		resources = plugin.ResourceTypes
+	}
+	theme := viper.GetString("theme")
+	if theme == "" {
+		theme = "default"
+	}
+	redact := listSetting("redact")
+	if redact == nil {
+		redact = []string{}
+	}
+	return map[string]interface{}{
+		"output":     viper.GetString("output"),
+		"resources":  resources,
+		"label-keys": labelKeys,
+		"theme":      theme,
+		"redact":     redact,
+	}
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View or create the pod-lens config file.",
+	}
+
+	view := &cobra.Command{
+		Use:   "view",
+		Short: "Print the effective configuration after merging the config file, context overrides, env vars and flags.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfig(); err != nil {
+				return err
+			}
+			data, err := yaml.Marshal(effectiveConfig())
+			if err != nil {
+				return err
+			}
+			source := viper.ConfigFileUsed()
+			if _, err := os.Stat(source); err != nil {
+				source += " (not found, using defaults)"
+			}
+			fmt.Printf("# config file: %s\n", source)
+			if configContext != "" {
+				fmt.Printf("# context overrides: %s\n", configContext)
+			}
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	var force bool
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a commented config file with the default settings.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			if _, err := os.Stat(path); err == nil && !force {
+				return errors.Errorf("%s already exists, use --force to overwrite it", path)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return errors.Wrap(err, "failed to create config directory")
+			}
+			if err := os.WriteFile(path, []byte(defaultConfig()), 0o644); err != nil {
+				return errors.Wrap(err, "failed to write config file")
+			}
+			fmt.Printf("Config written to %s\n", path)
+			return nil
+		},
+	}
+	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing config file")
+
+	cmd.AddCommand(view, initCmd)
+	return cmd
+}
+
+func defaultConfig() string {
+	return `# pod-lens configuration. Flags and POD_LENS_* env vars override these settings.
+
+# Default output format when -o is not set: json, yaml, dot or mermaid.
+output: ""
+
+# Related resource types to look up, all of them when empty. Any of:
+# ` + strings.Join(plugin.ResourceTypes, ", ") + `
+resources: []
+
+# Label keys tried in order when -l is not set. Join keys with + to require
+# all of them, e.g. app.kubernetes.io/name+app.kubernetes.io/instance.
+label-keys:
+  - ` + strings.Join(plugin.DefaultLabelKeys, "\n  - ") + `
+
+# Color theme: ` + strings.Join(themes, " or ") + `.
+theme: default
+
+# Regular expressions matched against env var names, annotation keys and
+# ConfigMap keys whose values are hidden in -o json and -o yaml.
+redact: []
+
+# Settings overridden for a kubeconfig context.
+contexts: {}
+#  production:
+#    theme: mono
+#    redact:
+#      - (?i)password|token|secret
+`
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestListSettingFromEnv(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.SetEnvPrefix("POD_LENS")
+	viper.AutomaticEnv()
+	t.Setenv("POD_LENS_REDACT", "password, token")
+	t.Setenv("POD_LENS_RESOURCES", "services")
+
+	want := []string{"password", "token"}
+	if got := listSetting("redact"); !reflect.DeepEqual(got, want) {
+		t.Errorf("listSetting(redact) = %q, want %q", got, want)
+	}
+	if got := effectiveConfig()["redact"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("effective redact = %q, want %q", got, want)
+	}
+	if got := listSetting("resources"); !reflect.DeepEqual(got, []string{"services"}) {
+		t.Errorf("listSetting(resources) = %q", got)
+	}
+}
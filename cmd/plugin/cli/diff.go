@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/sunny0826/kubectl-pod-lens/pkg/plugin"
+)
+
+func newDiffCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "diff podA podB",
+		Short: "Show what differs between two pods, e.g. a misbehaving replica and a healthy one.",
+		Example: `
+# Compare a crashing replica with a healthy one
+$ kubectl pod-lens diff web-7d4b9-abcde web-7d4b9-fghij
+# Print the differences as JSON
+$ kubectl pod-lens diff web-7d4b9-abcde web-7d4b9-fghij -n shop -o json
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfig(); err != nil {
+				return err
+			}
+			opts := plugin.Options{
+				FromDirs:  fromDirFlag,
+				FromFiles: fromFileFlag,
+				Output:    output,
+				Redact:    listSetting("redact"),
+			}
+			if err := plugin.RunDiff(KubernetesConfigFlags, args[0], args[1], opts); err != nil {
+				return errors.Cause(err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format. One of: json|yaml")
+	cmd.Flags().StringSliceVar(&fromFileFlag, "from-file", nil, "Read objects from YAML or JSON manifests instead of a cluster, '-' reads standard input")
+	cmd.Flags().StringSliceVar(&fromDirFlag, "from-dir", nil, "Read objects from all YAML and JSON files in a directory, e.g. a cluster-info dump")
+	return cmd
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"k8s.io/klog"
@@ -20,12 +21,22 @@ import (
 var (
 	KubernetesConfigFlags *genericclioptions.ConfigFlags
 	allNamespacesFlag     bool
+	checkAccessFlag       bool
+	fromDirFlag           []string
+	fromFileFlag          []string
 	labelFlag             string
+	logsFlag              int
+	logsGrepFlag          string
+	reportFlag            string
+	svcByLabelFlag        bool
+	strictFlag            bool
+	tuiFlag               bool
+	watchFlag             bool
 )
 
 func RootCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "kubectl pod-lens [pod name]",
+		Use:   "kubectl pod-lens [pod name | kind/name | node/name | ns/name]",
 		Short: "Show pod related resources.",
 		Long:  printLogo(),
 		Example: `
@@ -36,13 +47,43 @@ $ kubectl pod-lens prometheus-prometheus-operator-prometheus-0
 # Support input pod name fuzzy matching
 $ kubectl pod-lens prometheus-prometheus-operator-prometheus-
 $ kubectl pod-lens prometheus-prometheus-operator-prometheus-*
+# Start from a Deployment, StatefulSet, DaemonSet, Job or CronJob and summarize all its pods
+$ kubectl pod-lens deploy/prometheus-operator
+$ kubectl pod-lens sts/prometheus-prometheus-operator-prometheus
+# Show a node with its conditions, allocated resources, pods and PDBs blocking a drain
+$ kubectl pod-lens node/worker-1
+# Map the workloads of a namespace with their Services, Ingresses, storage and config, and the orphans
+$ kubectl pod-lens ns/monitoring
+# Show the last 50 log lines of each container, and of the previous instance of restarted ones
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --logs=50
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --logs-grep 'error|panic'
+# Redraw the tree on every change during a rollout, marking what changed
+$ kubectl pod-lens deploy/prometheus-operator --watch
+# Browse the related objects full screen with their details, YAML, events and logs
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --tui
+# Print the result as JSON or YAML
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 -o json
+# Export the relationship graph for Graphviz or Mermaid
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 -o dot | dot -Tsvg > pod.svg
+# Write a self-contained HTML report for incident handoffs
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --report out.html
+# Fail when the pod references missing ConfigMaps, Secrets, PVCs or keys
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --strict
+# Work offline from manifests or a cluster-info dump directory
+$ kubectl get all,cm,secret,pvc,ing -o yaml | kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --from-file -
+$ kubectl pod-lens prometheus-prometheus-operator-prometheus-0 --from-dir ./cluster-dump
 `,
+		Args:          cobra.ArbitraryArgs,
 		SilenceErrors: true,
 		SilenceUsage:  true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			_ = viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfig(); err != nil {
+				return err
+			}
+
 			var podName string
 			if len(args) > 0 {
 				podName = args[0]
@@ -51,7 +92,25 @@ $ kubectl pod-lens prometheus-prometheus-operator-prometheus-*
 			argsChannel := make(chan string, 1)
 			argsChannel <- podName
 
-			if err := plugin.RunPlugin(KubernetesConfigFlags, argsChannel, allNamespacesFlag, labelFlag); err != nil {
+			opts := plugin.Options{
+				AllNamespaces:  allNamespacesFlag,
+				LabelKeys:      listSetting("label-keys"),
+				LabelSelector:  labelFlag,
+				Logs:           logsFlag,
+				LogsGrep:       logsGrepFlag,
+				Output:         viper.GetString("output"),
+				Redact:         listSetting("redact"),
+				Report:         reportFlag,
+				Resources:      listSetting("resources"),
+				ServiceByLabel: svcByLabelFlag,
+				Strict:         strictFlag,
+				TUI:            tuiFlag,
+				Watch:          watchFlag,
+				CheckAccess:    checkAccessFlag,
+				FromDirs:       fromDirFlag,
+				FromFiles:      fromFileFlag,
+			}
+			if err := plugin.RunPlugin(KubernetesConfigFlags, argsChannel, opts); err != nil {
 				return errors.Cause(err)
 			}
 
@@ -62,9 +121,24 @@ $ kubectl pod-lens prometheus-prometheus-operator-prometheus-*
 	cobra.OnInitialize(initConfig)
 
 	KubernetesConfigFlags = genericclioptions.NewConfigFlags(false)
-	KubernetesConfigFlags.AddFlags(cmd.Flags())
+	KubernetesConfigFlags.AddFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().StringVar(&configFlag, "config", "", "Path to the config file (default "+defaultConfigPath()+")")
 	cmd.Flags().BoolVarP(&allNamespacesFlag, "all-namespaces", "A", false, "query all objects in all API groups, both namespaced and non-namespaced")
-	cmd.Flags().StringVarP(&labelFlag, "selector", "l", "", "Selector (label query) to filter on, only supports '=' and a parameter (e.g. -l key1=value1)")
+	cmd.Flags().StringVarP(&labelFlag, "selector", "l", "", "Selector (label query) to find related resources, supports '=', '==', '!=', 'in', 'notin' and existence (e.g. -l 'app.kubernetes.io/name=web,tier in (frontend,api)')")
+	cmd.Flags().StringSlice("label-keys", nil, "Ordered label keys used to find related resources when -l is not set, join keys with '+' to require all of them "+
+		"(default "+strings.Join(plugin.DefaultLabelKeys, ",")+")")
+	cmd.Flags().StringP("output", "o", "", "Output format. One of: json|yaml|dot|mermaid")
+	cmd.Flags().StringVar(&reportFlag, "report", "", "Write a self-contained HTML report to the given file")
+	cmd.Flags().BoolVar(&svcByLabelFlag, "svc-by-label", false, "Find services by their own labels instead of matching their selector against the pod")
+	cmd.Flags().BoolVar(&checkAccessFlag, "check-access", false, "Check up front which resource types the current user cannot list")
+	cmd.Flags().StringSliceVar(&fromFileFlag, "from-file", nil, "Read objects from YAML or JSON manifests instead of a cluster, '-' reads standard input")
+	cmd.Flags().StringSliceVar(&fromDirFlag, "from-dir", nil, "Read objects from all YAML and JSON files in a directory, e.g. a cluster-info dump")
+	cmd.Flags().BoolVar(&strictFlag, "strict", false, "Exit with a non-zero status when the pod references missing ConfigMaps, Secrets, PVCs or keys")
+	cmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "Watch the pod or workload and its related objects and redraw the tree on every change")
+	cmd.Flags().IntVar(&logsFlag, "logs", 0, "Show the last N log lines of each container under its entry, and of the previous instance of restarted containers")
+	cmd.Flags().Lookup("logs").NoOptDefVal = strconv.Itoa(plugin.DefaultLogLines)
+	cmd.Flags().StringVar(&logsGrepFlag, "logs-grep", "", "Only show log lines matching this regular expression, implies --logs")
+	cmd.Flags().BoolVar(&tuiFlag, "tui", false, "Browse the relationship tree full screen, with details, YAML, events, logs and describe of the selected object")
 
 	klog.InitFlags(nil)
 	cmd.Flags().AddGoFlagSet(flag.CommandLine)
@@ -74,22 +148,22 @@ $ kubectl pod-lens prometheus-prometheus-operator-prometheus-*
 			_ = cmd.Flags().MarkHidden(f.Name)
 		}
 	})
-	_ = cmd.Flags().MarkHidden("as-group")
-	_ = cmd.Flags().MarkHidden("as")
-	_ = cmd.Flags().MarkHidden("cache-dir")
-	_ = cmd.Flags().MarkHidden("certificate-authority")
-	_ = cmd.Flags().MarkHidden("client-certificate")
-	_ = cmd.Flags().MarkHidden("client-key")
-	_ = cmd.Flags().MarkHidden("cluster")
-	_ = cmd.Flags().MarkHidden("insecure-skip-tls-verify")
-	_ = cmd.Flags().MarkHidden("password")
-	_ = cmd.Flags().MarkHidden("request-timeout")
-	_ = cmd.Flags().MarkHidden("server")
-	_ = cmd.Flags().MarkHidden("token")
-	_ = cmd.Flags().MarkHidden("user")
-	_ = cmd.Flags().MarkHidden("username")
+	_ = cmd.PersistentFlags().MarkHidden("as-group")
+	_ = cmd.PersistentFlags().MarkHidden("as")
+	_ = cmd.PersistentFlags().MarkHidden("cache-dir")
+	_ = cmd.PersistentFlags().MarkHidden("certificate-authority")
+	_ = cmd.PersistentFlags().MarkHidden("client-certificate")
+	_ = cmd.PersistentFlags().MarkHidden("client-key")
+	_ = cmd.PersistentFlags().MarkHidden("cluster")
+	_ = cmd.PersistentFlags().MarkHidden("insecure-skip-tls-verify")
+	_ = cmd.PersistentFlags().MarkHidden("password")
+	_ = cmd.PersistentFlags().MarkHidden("server")
+	_ = cmd.PersistentFlags().MarkHidden("token")
+	_ = cmd.PersistentFlags().MarkHidden("user")
+	_ = cmd.PersistentFlags().MarkHidden("username")
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	cmd.AddCommand(newConfigCmd(), newUsesCmd(), newDiffCmd())
 	return cmd
 }
 
@@ -100,10 +174,6 @@ func InitAndExecute() {
 	}
 }
 
-func initConfig() {
-	viper.AutomaticEnv()
-}
-
 func printLogo() string {
 	return cfmt.Sprintf(`
 {{                           /$$         /$$                               }}::red
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/sunny0826/kubectl-pod-lens/pkg/plugin"
+)
+
+func newUsesCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "uses kind/name",
+		Short: "List the pods, grouped by workload, that use a ConfigMap, Secret or PVC.",
+		Example: `
+# Find every consumer before rotating a Secret
+$ kubectl pod-lens uses secret/db-creds
+# Search all namespaces and print JSON
+$ kubectl pod-lens uses configmap/ca-bundle -A -o json
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyConfig(); err != nil {
+				return err
+			}
+			opts := plugin.Options{
+				AllNamespaces: allNamespacesFlag,
+				FromDirs:      fromDirFlag,
+				FromFiles:     fromFileFlag,
+				Output:        output,
+			}
+			if err := plugin.RunUses(KubernetesConfigFlags, args[0], opts); err != nil {
+				return errors.Cause(err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespacesFlag, "all-namespaces", "A", false, "Search pods in all namespaces")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format. One of: json|yaml")
+	cmd.Flags().StringSliceVar(&fromFileFlag, "from-file", nil, "Read objects from YAML or JSON manifests instead of a cluster, '-' reads standard input")
+	cmd.Flags().StringSliceVar(&fromDirFlag, "from-dir", nil, "Read objects from all YAML and JSON files in a directory, e.g. a cluster-info dump")
+	return cmd
+}
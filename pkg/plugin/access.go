@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pterm/pterm"
+	authv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotVisible records a resource type that could not be read, so the rest of
+// the result can still be shown.
+type NotVisible struct {
+	Resource string `json:"resource"`
+	Reason   string `json:"reason"`
+}
+
+// accessCheck is a resource type pod-lens needs to list.
+type accessCheck struct {
+	Group      string
+	Resource   string
+	Namespaced bool
+}
+
+var accessChecks = []accessCheck{
+	{Resource: "nodes"},
+	{Resource: "pods"},
+	{Group: "apps", Resource: "replicasets", Namespaced: true},
+	{Group: "apps", Resource: "deployments", Namespaced: true},
+	{Group: "apps", Resource: "statefulsets", Namespaced: true},
+	{Group: "apps", Resource: "daemonsets", Namespaced: true},
+	{Resource: "services", Namespaced: true},
+	{Group: "networking.k8s.io", Resource: "ingresses", Namespaced: true},
+	{Group: gatewayGroup, Resource: "httproutes", Namespaced: true},
+	{Group: gatewayGroup, Resource: "grpcroutes", Namespaced: true},
+	{Resource: "persistentvolumeclaims", Namespaced: true},
+	{Resource: "configmaps", Namespaced: true},
+	{Resource: "secrets", Namespaced: true},
+	{Resource: "serviceaccounts", Namespaced: true},
+	{Group: "autoscaling", Resource: "horizontalpodautoscalers", Namespaced: true},
+	{Group: "policy", Resource: "poddisruptionbudgets", Namespaced: true},
+	{Resource: "events", Namespaced: true},
+	{Resource: "resourcequotas", Namespaced: true},
+}
+
+// notVisibleReason classifies errors that only hide one resource type:
+// RBAC denials, resources that do not exist and APIs the cluster does not serve.
+// A NotFound naming an object is about that object, not its type, and is left
+// to the caller.
+func notVisibleReason(err error) (string, bool) {
+	switch {
+	case apierrors.IsForbidden(err):
+		return "forbidden", true
+	case apierrors.IsNotFound(err):
+		var status apierrors.APIStatus
+		if errors.As(err, &status) && status.Status().Details != nil && status.Status().Details.Name != "" {
+			return "", false
+		}
+		return "not found", true
+	case apierrors.IsMethodNotSupported(err):
+		return "not supported", true
+	case meta.IsNoMatchError(err):
+		return "API not served", true
+	}
+	return "", false
+}
+
+// notVisible records the resource as not visible when err only hides that
+// resource type and reports whether it did so.
+func (sf *SnifferPlugin) notVisible(resource string, err error) bool {
+	reason, ok := notVisibleReason(err)
+	if !ok {
+		return false
+	}
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	for _, nv := range sf.AllInfo.NotVisible {
+		if nv.Resource == resource {
+			return true
+		}
+	}
+	sf.AllInfo.NotVisible = append(sf.AllInfo.NotVisible, NotVisible{Resource: resource, Reason: reason})
+	return true
+}
+
+// partial makes a lookup of a single resource type tolerate errors that only
+// hide that type.
+func (sf *SnifferPlugin) partial(resource string, t task) task {
+	return func(ctx context.Context) error {
+		if err := t(ctx); err != nil && !sf.notVisible(resource, err) {
+			return err
+		}
+		return nil
+	}
+}
+
+// checkAccess asks the API server up front which resource types the current
+// user may list, using SelfSubjectAccessReview.
+func (sf *SnifferPlugin) checkAccess(ctx context.Context, namespace string) ([]NotVisible, error) {
+	var denied []NotVisible
+	for _, c := range accessChecks {
+		attrs := &authv1.ResourceAttributes{Verb: "list", Group: c.Group, Resource: c.Resource}
+		if c.Namespaced {
+			attrs.Namespace = namespace
+		}
+		review, err := sf.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx,
+			&authv1.SelfSubjectAccessReview{Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs}},
+			metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if review.Status.Allowed {
+			continue
+		}
+		reason := review.Status.Reason
+		if reason == "" {
+			reason = "list not allowed"
+		}
+		denied = append(denied, NotVisible{Resource: c.Resource, Reason: reason})
+	}
+	return denied, nil
+}
+
+func printAccessCheck(namespace string, denied []NotVisible) {
+	if len(denied) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, pterm.Green("Access check: all resource types can be listed in namespace "+namespace))
+		return
+	}
+	table := uitable.New()
+	table.Wrap = true
+	table.AddRow("RESOURCE", "REASON")
+	for _, d := range denied {
+		table.AddRow(pterm.Yellow(d.Resource), d.Reason)
+	}
+	_, _ = cfmt.Fprintln(os.Stderr, "{{ Access Check }}::bgYellow|#000000")
+	_, _ = fmt.Fprintln(os.Stderr, table)
+}
+
+func (sf *SnifferPlugin) printNotVisible() {
+	if len(sf.AllInfo.NotVisible) == 0 {
+		return
+	}
+	table := uitable.New()
+	table.AddRow("")
+	for _, nv := range sf.AllInfo.NotVisible {
+		table.AddRow(pterm.Yellow(nv.Resource), pterm.Gray("not visible: "+nv.Reason))
+	}
+	_, _ = cfmt.Println("{{ Not Visible }}::bgYellow|#000000")
+	fmt.Println(table)
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	authv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckAccess(t *testing.T) {
+	sf, clientset := newTestPlugin()
+	var namespaces []string
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		namespaces = append(namespaces, attrs.Resource+"@"+attrs.Namespace)
+		switch attrs.Resource {
+		case "secrets":
+			review.Status = authv1.SubjectAccessReviewStatus{Reason: "RBAC: no role grants secrets"}
+		case "events":
+			review.Status = authv1.SubjectAccessReviewStatus{}
+		default:
+			review.Status = authv1.SubjectAccessReviewStatus{Allowed: true}
+		}
+		return true, review, nil
+	})
+
+	denied, err := sf.checkAccess(context.Background(), "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NotVisible{
+		{Resource: "secrets", Reason: "RBAC: no role grants secrets"},
+		{Resource: "events", Reason: "list not allowed"},
+	}
+	if !reflect.DeepEqual(denied, want) {
+		t.Errorf("checkAccess() = %v, want %v", denied, want)
+	}
+	if len(namespaces) != len(accessChecks) || namespaces[0] != "nodes@" || namespaces[len(namespaces)-1] != "resourcequotas@shop" {
+		t.Errorf("reviewed %v", namespaces)
+	}
+}
+
+func TestNotVisibleReason(t *testing.T) {
+	secrets := schema.GroupResource{Resource: "secrets"}
+	tests := []struct {
+		name   string
+		err    error
+		want   string
+		wantOK bool
+	}{
+		{"forbidden", apierrors.NewForbidden(secrets, "", nil), "forbidden", true},
+		{"wrapped forbidden", errors.Wrap(apierrors.NewForbidden(secrets, "db", nil), "get"), "forbidden", true},
+		{"resource type not served", apierrors.NewNotFound(secrets, ""), "not found", true},
+		{"missing object", apierrors.NewNotFound(secrets, "db"), "", false},
+		{"other error", fmt.Errorf("connection refused"), "", false},
+		{"no error", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := notVisibleReason(tt.err)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("notVisibleReason() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
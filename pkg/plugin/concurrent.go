@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// maxConcurrentRequests bounds the number of API calls in flight at once.
+const maxConcurrentRequests = 8
+
+type task func(ctx context.Context) error
+
+// semaphoreKey carries the pool of the outermost runConcurrently to the tasks.
+type semaphoreKey struct{}
+
+// runConcurrently runs the tasks on a bounded pool sharing ctx, skipping nil
+// tasks. The first failure cancels the remaining tasks and is returned.
+//
+// Nested calls made from within a task share the outermost pool, so at most
+// maxConcurrentRequests tasks run at once however deep the lookups go. The
+// calling task hands its slot to its subtasks while it waits for them.
+func runConcurrently(ctx context.Context, tasks ...task) error {
+	sem, nested := ctx.Value(semaphoreKey{}).(chan struct{})
+	if nested {
+		<-sem
+		defer func() { sem <- struct{}{} }()
+	} else {
+		sem = make(chan struct{}, maxConcurrentRequests)
+		ctx = context.WithValue(ctx, semaphoreKey{}, sem)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, t := range tasks {
+		if t == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			if firstErr != nil {
+				return firstErr
+			}
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(t task) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := t(ctx); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(t)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// requestTimeout parses --request-timeout the same way kubectl does: a bare
+// integer is a number of seconds and zero means no timeout.
+func requestTimeout(configFlags *genericclioptions.ConfigFlags) (time.Duration, error) {
+	if configFlags.Timeout == nil || *configFlags.Timeout == "" {
+		return 0, nil
+	}
+	value := *configFlags.Timeout
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, errors.Errorf("invalid --request-timeout %q, must be a duration like 1s, 2m or 3h", *configFlags.Timeout)
+	}
+	return timeout, nil
+}
+
+// contextError turns a cancelled or expired context into a readable error.
+func contextError(ctx context.Context, timeout time.Duration, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return errors.Errorf("timed out after %s while fetching related resources, use --request-timeout to allow more time", timeout)
+	case context.Canceled:
+		return errors.New("interrupted")
+	}
+	return err
+}
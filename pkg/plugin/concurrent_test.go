@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRunConcurrentlyNestedBound(t *testing.T) {
+	var running, peak, done int32
+	leaf := func(ctx context.Context) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&done, 1)
+		return nil
+	}
+	var outer []task
+	for i := 0; i < 2*maxConcurrentRequests; i++ {
+		outer = append(outer, func(ctx context.Context) error {
+			if err := leaf(ctx); err != nil {
+				return err
+			}
+			var inner []task
+			for j := 0; j < maxConcurrentRequests; j++ {
+				inner = append(inner, leaf)
+			}
+			return runConcurrently(ctx, inner...)
+		})
+	}
+	if err := runConcurrently(context.Background(), outer...); err != nil {
+		t.Fatal(err)
+	}
+	if want := int32(2*maxConcurrentRequests + 2*maxConcurrentRequests*maxConcurrentRequests); done != want {
+		t.Errorf("ran %d tasks, want %d", done, want)
+	}
+	if peak > maxConcurrentRequests {
+		t.Errorf("peak concurrency = %d, want at most %d", peak, maxConcurrentRequests)
+	}
+}
+
+func TestRunConcurrentlyFirstError(t *testing.T) {
+	failed := errors.New("failed")
+	err := runConcurrently(context.Background(),
+		nil,
+		func(ctx context.Context) error {
+			return runConcurrently(ctx, func(context.Context) error { return failed })
+		},
+		func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() },
+	)
+	if err != failed {
+		t.Errorf("runConcurrently() = %v, want %v", err, failed)
+	}
+}
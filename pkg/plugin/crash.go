@@ -0,0 +1,186 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const maxTerminationMessage = 80
+
+var exitCodeMeanings = map[int32]string{
+	0:   "completed successfully",
+	1:   "application error",
+	2:   "misuse of shell builtin or invalid arguments",
+	126: "command cannot execute (permission denied or not executable)",
+	127: "command not found (check image entrypoint and command)",
+	128: "invalid exit argument",
+	255: "exit status out of range",
+}
+
+var signalNames = map[int32]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	15: "SIGTERM",
+}
+
+var signalMeanings = map[int32]string{
+	2:  "interrupted",
+	6:  "aborted by the process itself",
+	9:  "killed, usually by the OOM killer or after the termination grace period",
+	11: "segmentation fault",
+	15: "terminated gracefully, e.g. by a failed liveness probe or a rollout",
+}
+
+// ContainerDiagnosis explains the last termination of a container.
+type ContainerDiagnosis struct {
+	Container  string       `json:"container"`
+	Restarts   int32        `json:"restarts"`
+	Reason     string       `json:"reason,omitempty"`
+	ExitCode   int32        `json:"exitCode"`
+	Signal     int32        `json:"signal,omitempty"`
+	SignalName string       `json:"signalName,omitempty"`
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	Meaning    string       `json:"meaning,omitempty"`
+	Hints      []string     `json:"hints,omitempty"`
+}
+
+func (sf *SnifferPlugin) diagnoseContainers() []ContainerDiagnosis {
+	var diagnoses []ContainerDiagnosis
+	limits := make(map[string]v1.ResourceList)
+	for _, c := range sf.PodObject.Spec.InitContainers {
+		limits[c.Name] = c.Resources.Limits
+	}
+	for _, c := range sf.PodObject.Spec.Containers {
+		limits[c.Name] = c.Resources.Limits
+	}
+	statuses := append(append([]v1.ContainerStatus{}, sf.PodObject.Status.InitContainerStatuses...),
+		sf.PodObject.Status.ContainerStatuses...)
+	for _, val := range statuses {
+		term := val.LastTerminationState.Terminated
+		if term == nil && val.State.Terminated != nil && val.State.Terminated.ExitCode != 0 {
+			term = val.State.Terminated
+		}
+		if term == nil {
+			continue
+		}
+		d := ContainerDiagnosis{
+			Container: val.Name,
+			Restarts:  val.RestartCount,
+			Reason:    term.Reason,
+			ExitCode:  term.ExitCode,
+			Signal:    term.Signal,
+			Message:   strings.TrimSpace(term.Message),
+		}
+		if !term.FinishedAt.IsZero() {
+			d.FinishedAt = term.FinishedAt.DeepCopy()
+		}
+		d.Meaning, d.SignalName = exitCodeMeaning(term.ExitCode, term.Signal)
+		if term.Reason == "OOMKilled" {
+			hint := "container exceeded its memory limit"
+			if mem, ok := limits[val.Name][v1.ResourceMemory]; ok {
+				hint += " (" + mem.String() + ")"
+			}
+			d.Hints = append(d.Hints, hint)
+		}
+		if n := sf.livenessFailures(val.Name); n > 0 && val.RestartCount > 0 {
+			d.Hints = append(d.Hints, fmt.Sprintf("liveness probe failed %d time(s), restarts are likely caused by the probe", n))
+		}
+		diagnoses = append(diagnoses, d)
+	}
+	return diagnoses
+}
+
+// exitCodeMeaning decodes common exit codes, including 128+n signal exits.
+func exitCodeMeaning(exitCode, signal int32) (string, string) {
+	if signal == 0 && exitCode > 128 && exitCode < 160 {
+		signal = exitCode - 128
+	}
+	if signal != 0 {
+		name := signalNames[signal]
+		if name == "" {
+			name = fmt.Sprintf("signal %d", signal)
+		}
+		meaning, ok := signalMeanings[signal]
+		if !ok {
+			meaning = "killed by " + name
+		}
+		return meaning, name
+	}
+	return exitCodeMeanings[exitCode], ""
+}
+
+// livenessFailures counts liveness probe failures reported for the container.
+// Events without a field path are only attributed when the pod has a single
+// container.
+func (sf *SnifferPlugin) livenessFailures(container string) int32 {
+	var count int32
+	fieldPath := fmt.Sprintf("{%s}", container)
+	single := len(sf.PodObject.Spec.Containers) == 1
+	for _, e := range sf.AllInfo.Events {
+		if e.Object.Kind != "Pod" || e.Reason != "Unhealthy" || !strings.HasPrefix(e.Message, "Liveness probe failed") {
+			continue
+		}
+		if e.FieldPath == "" && single || strings.HasSuffix(e.FieldPath, fieldPath) {
+			count += e.Count
+		}
+	}
+	return count
+}
+
+func (sf *SnifferPlugin) diagnosis(container string) *ContainerDiagnosis {
+	for i := range sf.AllInfo.Diagnostics {
+		if sf.AllInfo.Diagnostics[i].Container == container {
+			return &sf.AllInfo.Diagnostics[i]
+		}
+	}
+	return nil
+}
+
+// diagnosisLeveledList returns tree items for a container's last termination
+// together with the matching lines of the state column.
+func (sf *SnifferPlugin) diagnosisLeveledList(container string) ([]pterm.LeveledListItem, string) {
+	d := sf.diagnosis(container)
+	if d == nil {
+		return nil, ""
+	}
+	var leveledList []pterm.LeveledListItem
+	var stateList string
+	add := func(label, state string) {
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 5, Text: pterm.Gray(label)})
+		stateList += state + "\n"
+	}
+
+	last := fmt.Sprintf("[%s] exit %d", d.Reason, d.ExitCode)
+	if d.SignalName != "" {
+		last += " " + d.SignalName
+	}
+	if d.FinishedAt != nil {
+		last += " at " + d.FinishedAt.Format("2006-01-02 15:04:05")
+	}
+	add("last terminated", pterm.Red(last))
+	if d.Meaning != "" {
+		add("meaning", pterm.Yellow(d.Meaning))
+	}
+	if d.Message != "" {
+		msg := strings.SplitN(d.Message, "\n", 2)[0]
+		add("message", runewidth.Truncate(msg, maxTerminationMessage, "..."))
+	}
+	for _, h := range d.Hints {
+		add("hint", pterm.LightYellow(h))
+	}
+	return leveledList, stateList
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestExitCodeMeaning(t *testing.T) {
+	tests := []struct {
+		exitCode    int32
+		signal      int32
+		wantMeaning string
+		wantSignal  string
+	}{
+		{137, 0, signalMeanings[9], "SIGKILL"},
+		{143, 0, signalMeanings[15], "SIGTERM"},
+		{139, 0, signalMeanings[11], "SIGSEGV"},
+		{1, 0, "application error", ""},
+		{127, 0, "command not found (check image entrypoint and command)", ""},
+		{0, 6, "aborted by the process itself", "SIGABRT"},
+		{0, 10, "killed by signal 10", "signal 10"},
+		{42, 0, "", ""},
+	}
+	for _, tt := range tests {
+		meaning, signal := exitCodeMeaning(tt.exitCode, tt.signal)
+		if meaning != tt.wantMeaning || signal != tt.wantSignal {
+			t.Errorf("exitCodeMeaning(%d, %d) = %q, %q, want %q, %q",
+				tt.exitCode, tt.signal, meaning, signal, tt.wantMeaning, tt.wantSignal)
+		}
+	}
+}
+
+func TestDiagnoseContainers(t *testing.T) {
+	oomKilled := func(name string) v1.ContainerStatus {
+		return v1.ContainerStatus{Name: name, RestartCount: 2, LastTerminationState: v1.ContainerState{
+			Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}}}
+	}
+	liveness := func(fieldPath string) TimelineEvent {
+		return TimelineEvent{Object: ObjectRef{Kind: "Pod"}, FieldPath: fieldPath, Reason: "Unhealthy",
+			Message: "Liveness probe failed: connection refused", Count: 3}
+	}
+
+	tests := []struct {
+		name       string
+		containers []v1.Container
+		statuses   []v1.ContainerStatus
+		events     []TimelineEvent
+		want       map[string][]string
+	}{
+		{
+			name: "OOMKilled with a limit",
+			containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{
+				Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")}}}},
+			statuses: []v1.ContainerStatus{oomKilled("app")},
+			want:     map[string][]string{"app": {"container exceeded its memory limit (128Mi)"}},
+		},
+		{
+			name:       "OOMKilled without a limit",
+			containers: []v1.Container{{Name: "app"}},
+			statuses:   []v1.ContainerStatus{oomKilled("app")},
+			want:       map[string][]string{"app": {"container exceeded its memory limit"}},
+		},
+		{
+			name:       "liveness event without field path in a single container pod",
+			containers: []v1.Container{{Name: "app"}},
+			statuses:   []v1.ContainerStatus{oomKilled("app")},
+			events:     []TimelineEvent{liveness("")},
+			want: map[string][]string{"app": {"container exceeded its memory limit",
+				"liveness probe failed 3 time(s), restarts are likely caused by the probe"}},
+		},
+		{
+			name:       "liveness events in a multi-container pod",
+			containers: []v1.Container{{Name: "app"}, {Name: "sidecar"}},
+			statuses:   []v1.ContainerStatus{oomKilled("app"), oomKilled("sidecar")},
+			events:     []TimelineEvent{liveness(""), liveness("spec.containers{sidecar}")},
+			want: map[string][]string{
+				"app": {"container exceeded its memory limit"},
+				"sidecar": {"container exceeded its memory limit",
+					"liveness probe failed 3 time(s), restarts are likely caused by the probe"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin()
+			sf.PodObject = testPod()
+			sf.PodObject.Spec.Containers = tt.containers
+			sf.PodObject.Status.ContainerStatuses = tt.statuses
+			sf.AllInfo.Events = tt.events
+			diagnoses := sf.diagnoseContainers()
+			if len(diagnoses) != len(tt.want) {
+				t.Fatalf("diagnoseContainers() = %+v", diagnoses)
+			}
+			for _, d := range diagnoses {
+				if d.SignalName != "SIGKILL" || d.FinishedAt != nil {
+					t.Errorf("%s: signal %q, finishedAt %v", d.Container, d.SignalName, d.FinishedAt)
+				}
+				if got, want := strings.Join(d.Hints, "|"), strings.Join(tt.want[d.Container], "|"); got != want {
+					t.Errorf("%s hints = %q, want %q", d.Container, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnosisMessageTruncation(t *testing.T) {
+	sf, _ := newTestPlugin()
+	sf.AllInfo.Diagnostics = []ContainerDiagnosis{{Container: "app", Reason: "Error", ExitCode: 1,
+		Message: strings.Repeat("é", 100) + "\nsecond line"}}
+	_, state := sf.diagnosisLeveledList("app")
+	msg := strings.Split(pterm.RemoveColorFromString(state), "\n")[1]
+	if !utf8.ValidString(msg) || !strings.HasSuffix(msg, "...") || runewidth.StringWidth(msg) > maxTerminationMessage {
+		t.Errorf("message = %q", msg)
+	}
+}
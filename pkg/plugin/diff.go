@@ -0,0 +1,519 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const diffDocumentKind = "PodLensDiff"
+
+var diffSections = []string{"Spec", "Status", "Node", "References"}
+
+// generatedLabels and generatedAnnotations differ between replicas by
+// design and say nothing about why one of them misbehaves.
+var (
+	generatedLabels = map[string]bool{
+		"pod-template-hash":                        true,
+		"controller-revision-hash":                 true,
+		"statefulset.kubernetes.io/pod-name":       true,
+		"apps.kubernetes.io/pod-index":             true,
+		"batch.kubernetes.io/job-completion-index": true,
+	}
+	generatedAnnotations = []string{
+		lastAppliedAnnotation,
+		"kubernetes.io/config.seen",
+		"batch.kubernetes.io/job-completion-index",
+		"cni.projectcalico.org/",
+		"k8s.v1.cni.cncf.io/",
+	}
+)
+
+// Difference is a field that differs between two pods. A or B is empty when
+// the field is only set on the other pod.
+type Difference struct {
+	Section string `json:"section"`
+	Field   string `json:"field"`
+	A       string `json:"a,omitempty"`
+	B       string `json:"b,omitempty"`
+}
+
+// DiffDocument is the machine-readable form of a pod comparison.
+type DiffDocument struct {
+	APIVersion  string       `json:"apiVersion"`
+	Kind        string       `json:"kind"`
+	A           ObjectRef    `json:"a"`
+	B           ObjectRef    `json:"b"`
+	Differences []Difference `json:"differences"`
+	NotVisible  []NotVisible `json:"notVisible,omitempty"`
+}
+
+// podFacts flattens what is worth comparing about a pod into field/value
+// pairs per section, leaving out names, IPs, timestamps and other generated
+// fields.
+type podFacts map[string]map[string]string
+
+func (f podFacts) set(section, field, value string) {
+	if value == "" {
+		return
+	}
+	if f[section] == nil {
+		f[section] = make(map[string]string)
+	}
+	f[section][field] = value
+}
+
+func generatedAnnotation(key string) bool {
+	for _, a := range generatedAnnotations {
+		if key == a || strings.HasSuffix(a, "/") && strings.HasPrefix(key, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// stableVolumeName drops the random suffix of the service account token
+// volume, which would otherwise show up as a volume only each pod has.
+func stableVolumeName(name string) string {
+	if strings.HasPrefix(name, "kube-api-access-") {
+		return "kube-api-access"
+	}
+	return name
+}
+
+func envValue(e v1.EnvVar) string {
+	from := e.ValueFrom
+	switch {
+	case from == nil && e.Value == "":
+		return `""`
+	case from == nil:
+		return e.Value
+	case from.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("configMap %s key %s", from.ConfigMapKeyRef.Name, from.ConfigMapKeyRef.Key)
+	case from.SecretKeyRef != nil:
+		return fmt.Sprintf("secret %s key %s", from.SecretKeyRef.Name, from.SecretKeyRef.Key)
+	case from.FieldRef != nil:
+		return "field " + from.FieldRef.FieldPath
+	case from.ResourceFieldRef != nil:
+		return "resource " + from.ResourceFieldRef.Resource
+	}
+	return "<unknown>"
+}
+
+func volumeSource(vol v1.Volume) string {
+	switch {
+	case vol.ConfigMap != nil:
+		return "configMap " + vol.ConfigMap.Name
+	case vol.Secret != nil:
+		return "secret " + vol.Secret.SecretName
+	case vol.PersistentVolumeClaim != nil:
+		return "pvc " + vol.PersistentVolumeClaim.ClaimName
+	case vol.HostPath != nil:
+		return "hostPath " + vol.HostPath.Path
+	case vol.EmptyDir != nil:
+		return strings.TrimSpace("emptyDir " + string(vol.EmptyDir.Medium))
+	case vol.Projected != nil:
+		var sources []string
+		for _, s := range vol.Projected.Sources {
+			switch {
+			case s.ConfigMap != nil:
+				sources = append(sources, "configMap "+s.ConfigMap.Name)
+			case s.Secret != nil:
+				sources = append(sources, "secret "+s.Secret.Name)
+			case s.ServiceAccountToken != nil:
+				sources = append(sources, "serviceAccountToken")
+			case s.DownwardAPI != nil:
+				sources = append(sources, "downwardAPI")
+			}
+		}
+		return "projected " + strings.Join(sources, ", ")
+	}
+	data, _ := json.Marshal(vol.VolumeSource)
+	return string(data)
+}
+
+// imageDigest keeps the digest of a container image ID, whose prefix
+// depends on the container runtime.
+func imageDigest(id string) string {
+	if i := strings.LastIndex(id, "@"); i >= 0 {
+		return id[i+1:]
+	}
+	return id
+}
+
+func specFacts(f podFacts, pod *v1.Pod) {
+	spec := pod.Spec
+	f.set("Spec", "nodeName", spec.NodeName)
+	f.set("Spec", "serviceAccountName", spec.ServiceAccountName)
+	f.set("Spec", "priorityClassName", spec.PriorityClassName)
+	if spec.RuntimeClassName != nil {
+		f.set("Spec", "runtimeClassName", *spec.RuntimeClassName)
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		f.set("Spec", "owner", owner.Kind+"/"+owner.Name)
+	} else if len(pod.OwnerReferences) > 0 {
+		f.set("Spec", "owner", pod.OwnerReferences[0].Kind+"/"+pod.OwnerReferences[0].Name)
+	}
+	for k, v := range spec.NodeSelector {
+		f.set("Spec", "nodeSelector/"+k, v)
+	}
+	for _, t := range spec.Tolerations {
+		f.set("Spec", "tolerations/"+t.Key+":"+string(t.Effect), strings.TrimSpace(string(t.Operator)+" "+t.Value))
+	}
+	for k, v := range pod.Labels {
+		if !generatedLabels[k] {
+			f.set("Spec", "labels/"+k, v)
+		}
+	}
+	for k, v := range pod.Annotations {
+		if !generatedAnnotation(k) {
+			f.set("Spec", "annotations/"+k, v)
+		}
+	}
+	for _, vol := range spec.Volumes {
+		f.set("Spec", "volumes/"+stableVolumeName(vol.Name), volumeSource(vol))
+	}
+	containers := func(prefix string, list []v1.Container) {
+		for _, c := range list {
+			p := prefix + "/" + c.Name + "/"
+			f.set("Spec", p+"image", c.Image)
+			f.set("Spec", p+"command", strings.Join(c.Command, " "))
+			f.set("Spec", p+"args", strings.Join(c.Args, " "))
+			for _, e := range c.Env {
+				f.set("Spec", p+"env/"+e.Name, envValue(e))
+			}
+			for _, e := range c.EnvFrom {
+				value := "all keys"
+				if e.Prefix != "" {
+					value = "prefix " + e.Prefix
+				}
+				if e.ConfigMapRef != nil {
+					f.set("Spec", p+"envFrom/configMap "+e.ConfigMapRef.Name, value)
+				}
+				if e.SecretRef != nil {
+					f.set("Spec", p+"envFrom/secret "+e.SecretRef.Name, value)
+				}
+			}
+			for name, q := range c.Resources.Requests {
+				f.set("Spec", p+"requests/"+string(name), q.String())
+			}
+			for name, q := range c.Resources.Limits {
+				f.set("Spec", p+"limits/"+string(name), q.String())
+			}
+			for _, m := range c.VolumeMounts {
+				mount := stableVolumeName(m.Name)
+				if m.SubPath != "" {
+					mount += " subPath " + m.SubPath
+				}
+				if m.ReadOnly {
+					mount += " (ro)"
+				}
+				f.set("Spec", p+"mounts/"+m.MountPath, mount)
+			}
+		}
+	}
+	containers("initContainers", spec.InitContainers)
+	containers("containers", spec.Containers)
+}
+
+func statusFacts(f podFacts, pod *v1.Pod) {
+	status := pod.Status
+	f.set("Status", "phase", string(status.Phase))
+	f.set("Status", "reason", status.Reason)
+	f.set("Status", "qosClass", string(status.QOSClass))
+	for _, c := range status.Conditions {
+		f.set("Status", "conditions/"+string(c.Type), strings.TrimSpace(string(c.Status)+" "+c.Reason))
+	}
+	containers := func(prefix string, list []v1.ContainerStatus) {
+		for _, c := range list {
+			p := prefix + "/" + c.Name + "/"
+			f.set("Status", p+"state", containerState(c.State))
+			f.set("Status", p+"ready", strconv.FormatBool(c.Ready))
+			f.set("Status", p+"restarts", strconv.Itoa(int(c.RestartCount)))
+			f.set("Status", p+"imageDigest", imageDigest(c.ImageID))
+			if last := c.LastTerminationState.Terminated; last != nil {
+				f.set("Status", p+"lastTermination", fmt.Sprintf("%s (exit %d)", last.Reason, last.ExitCode))
+			}
+		}
+	}
+	containers("initContainers", status.InitContainerStatuses)
+	containers("containers", status.ContainerStatuses)
+}
+
+func nodeFacts(f podFacts, node *v1.Node) {
+	if node == nil {
+		return
+	}
+	info := node.Status.NodeInfo
+	f.set("Node", "kubeletVersion", info.KubeletVersion)
+	f.set("Node", "containerRuntimeVersion", info.ContainerRuntimeVersion)
+	f.set("Node", "osImage", info.OSImage)
+	f.set("Node", "kernelVersion", info.KernelVersion)
+	f.set("Node", "architecture", info.Architecture)
+	if node.Spec.Unschedulable {
+		f.set("Node", "unschedulable", "true")
+	}
+	for _, c := range node.Status.Conditions {
+		f.set("Node", "conditions/"+string(c.Type), string(c.Status))
+	}
+	for k, v := range node.Labels {
+		if k != v1.LabelHostname {
+			f.set("Node", "labels/"+k, v)
+		}
+	}
+	for _, t := range node.Spec.Taints {
+		value := t.Value
+		if value == "" {
+			value = "set"
+		}
+		f.set("Node", "taints/"+t.Key+":"+string(t.Effect), value)
+	}
+	for name, q := range node.Status.Allocatable {
+		f.set("Node", "allocatable/"+string(name), q.String())
+	}
+}
+
+// diffFacts lists the fields whose values differ, sorted by section and
+// field.
+func diffFacts(a, b podFacts) []Difference {
+	diffs := []Difference{}
+	for _, section := range diffSections {
+		fields := make(map[string]bool)
+		for k := range a[section] {
+			fields[k] = true
+		}
+		for k := range b[section] {
+			fields[k] = true
+		}
+		names := make([]string, 0, len(fields))
+		for k := range fields {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			if va, vb := a[section][k], b[section][k]; va != vb {
+				diffs = append(diffs, Difference{Section: section, Field: k, A: va, B: vb})
+			}
+		}
+	}
+	return diffs
+}
+
+// getPod looks a pod up by name; an empty namespace searches all of them.
+func (sf *SnifferPlugin) getPod(ctx context.Context, name, namespace string) (*v1.Pod, error) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "pods/"), "pod/")
+	if namespace != "" {
+		pod, err := sf.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("pod %s not found in namespace %s", name, namespace)
+		}
+		return pod, errors.Wrapf(err, "failed to get pod %s", name)
+	}
+	pods, err := sf.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+	var found *v1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, errors.Errorf("pod %s exists in namespaces %s and %s, set one with -n", name, found.Namespace, pods.Items[i].Namespace)
+		}
+		found = &pods.Items[i]
+	}
+	if found == nil {
+		return nil, errors.Errorf("pod %s not found", name)
+	}
+	return found, nil
+}
+
+func (sf *SnifferPlugin) getNode(ctx context.Context, name string) (*v1.Node, error) {
+	if name == "" {
+		return nil, nil
+	}
+	node, err := sf.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || sf.notVisible("nodes", err) {
+		return nil, nil
+	}
+	return node, errors.Wrapf(err, "failed to get node %s", name)
+}
+
+// referenceFacts records the resourceVersion of every ConfigMap and Secret
+// the pod references, never their data.
+func (sf *SnifferPlugin) referenceFacts(ctx context.Context, f podFacts, pod *v1.Pod) error {
+	for _, ref := range podReferences(pod) {
+		field := ref.Kind + "/" + ref.Name
+		if _, ok := f["References"][field]; ok {
+			continue
+		}
+		var (
+			meta metav1.Object
+			err  error
+		)
+		switch ref.Kind {
+		case "ConfigMap":
+			meta, err = sf.Clientset.CoreV1().ConfigMaps(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		case "Secret":
+			meta, err = sf.Clientset.CoreV1().Secrets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		default:
+			continue
+		}
+		switch {
+		case apierrors.IsNotFound(err):
+			f.set("References", field, "missing")
+		case sf.notVisible(referenceResources[ref.Kind], err):
+			f.set("References", field, "forbidden")
+		case err != nil:
+			return errors.Wrapf(err, "failed to get %s %s", ref.Kind, ref.Name)
+		default:
+			f.set("References", field, "resourceVersion "+meta.GetResourceVersion())
+		}
+	}
+	return nil
+}
+
+func (sf *SnifferPlugin) podFacts(ctx context.Context, pod *v1.Pod) (podFacts, error) {
+	pod = pod.DeepCopy()
+	sf.redactPod(pod)
+	pod.Annotations = sf.redactMap(pod.Annotations)
+	f := podFacts{}
+	specFacts(f, pod)
+	statusFacts(f, pod)
+	node, err := sf.getNode(ctx, pod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+	nodeFacts(f, node)
+	if err := sf.referenceFacts(ctx, f, pod); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// diffPods compares two pods, typically replicas of the same workload.
+func (sf *SnifferPlugin) diffPods(ctx context.Context, a, b *v1.Pod) ([]Difference, error) {
+	var facts [2]podFacts
+	tasks := make([]task, 0, 2)
+	for i, pod := range []*v1.Pod{a, b} {
+		i, pod := i, pod
+		tasks = append(tasks, func(ctx context.Context) error {
+			var err error
+			facts[i], err = sf.podFacts(ctx, pod)
+			return err
+		})
+	}
+	if err := runConcurrently(ctx, tasks...); err != nil {
+		return nil, err
+	}
+	return diffFacts(facts[0], facts[1]), nil
+}
+
+// RunDiff compares two pods and prints what differs between them.
+func RunDiff(configFlags *genericclioptions.ConfigFlags, nameA, nameB string, opts Options) error {
+	if opts.Output != "" && opts.Output != "json" && opts.Output != "yaml" {
+		return errors.Errorf("unsupported output format %q, expected one of [json yaml]", opts.Output)
+	}
+	sf, err := newPluginForOptions(configFlags, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	timeout, err := requestTimeout(configFlags)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	namespace := lookupNamespace(configFlags, opts)
+	a, err := sf.getPod(ctx, nameA, namespace)
+	if err != nil {
+		return contextError(ctx, timeout, err)
+	}
+	b, err := sf.getPod(ctx, nameB, namespace)
+	if err != nil {
+		return contextError(ctx, timeout, err)
+	}
+	if a.Namespace == b.Namespace && a.Name == b.Name {
+		return errors.Errorf("pod %s is compared with itself, pass two different pods", a.Name)
+	}
+	diffs, err := sf.diffPods(ctx, a, b)
+	if err != nil {
+		return contextError(ctx, timeout, err)
+	}
+
+	doc := &DiffDocument{
+		APIVersion:  documentAPIVersion,
+		Kind:        diffDocumentKind,
+		A:           newObjectRef("Pod", a),
+		B:           newObjectRef("Pod", b),
+		Differences: diffs,
+		NotVisible:  sf.AllInfo.NotVisible,
+	}
+	if opts.Output != "" {
+		return writeDocument(os.Stdout, opts.Output, doc)
+	}
+	printDiff(doc)
+	sf.printNotVisible()
+	return nil
+}
+
+func printDiff(doc *DiffDocument) {
+	nameA, nameB := doc.A.Name, doc.B.Name
+	if doc.A.Namespace != doc.B.Namespace {
+		nameA, nameB = doc.A.Namespace+"/"+nameA, doc.B.Namespace+"/"+nameB
+	}
+	_, _ = cfmt.Printf("{{ [Pod] }}::blue|bold %s {{vs}}::gray {{ [Pod] }}::blue|bold %s\n", nameA, nameB)
+	if len(doc.Differences) == 0 {
+		fmt.Println("No differences in spec, status, node or referenced ConfigMaps and Secrets.")
+		return
+	}
+	value := func(v string) string {
+		if v == "" {
+			return pterm.Gray("<none>")
+		}
+		return v
+	}
+	for _, section := range diffSections {
+		table := uitable.New()
+		table.Wrap = true
+		table.MaxColWidth = 60
+		table.AddRow("")
+		table.AddRow("FIELD", nameA, nameB)
+		rows := 0
+		for _, d := range doc.Differences {
+			if d.Section == section {
+				table.AddRow(d.Field, value(d.A), value(d.B))
+				rows++
+			}
+		}
+		if rows == 0 {
+			continue
+		}
+		_, _ = cfmt.Printf("{{ %s }}::bgMagenta|#ffffff\n", section)
+		fmt.Println(table)
+	}
+	fmt.Printf("%d difference(s).\n", len(doc.Differences))
+}
@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDiffPods(t *testing.T) {
+	replica := func(name, hash, node string, restarts int32) *v1.Pod {
+		pod := workloadPod(name, metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-7d4b9"},
+			map[string]string{"app": "web", "pod-template-hash": hash})
+		pod.UID = types.UID("uid-" + name)
+		pod.Spec.NodeName = node
+		pod.Spec.Volumes = []v1.Volume{
+			{Name: "kube-api-access-" + hash, VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{}}},
+			{Name: "tls", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "web-tls"}}},
+		}
+		pod.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{
+			{Name: "kube-api-access-" + hash, MountPath: "/var/run/secrets/kubernetes.io/serviceaccount", ReadOnly: true},
+		}
+		pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: "DB_PASSWORD", Value: "hunter2-" + name}}
+		pod.Status.PodIP = "10.0.0." + hash
+		pod.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "app", RestartCount: restarts, Ready: restarts == 0,
+			ContainerID: "containerd://" + name, ImageID: "docker.io/library/nginx@sha256:aaa",
+			State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}}
+		return pod
+	}
+	a := replica("web-7d4b9-abcde", "1", "node-1", 5)
+	a.Spec.Containers[0].Resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")}
+	b := replica("web-7d4b9-fghij", "2", "node-2", 0)
+	node := func(name, version string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelHostname: name}},
+			Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KubeletVersion: version}}}
+	}
+	secret := &v1.Secret{ObjectMeta: objectMeta("web-tls", nil), Data: map[string][]byte{"tls.key": []byte("secret")}}
+	secret.ResourceVersion = "42"
+
+	sf, _ := newTestPlugin(a, b, node("node-1", "v1.26.1"), node("node-2", "v1.25.3"), secret)
+	sf.redact = []*regexp.Regexp{regexp.MustCompile("PASSWORD")}
+	got, err := sf.diffPods(context.Background(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Difference{
+		{Section: "Spec", Field: "containers/app/limits/memory", A: "128Mi"},
+		{Section: "Spec", Field: "nodeName", A: "node-1", B: "node-2"},
+		{Section: "Status", Field: "containers/app/ready", A: "false", B: "true"},
+		{Section: "Status", Field: "containers/app/restarts", A: "5", B: "0"},
+		{Section: "Node", Field: "kubeletVersion", A: "v1.26.1", B: "v1.25.3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffPods() =\n%v\nwant\n%v", got, want)
+	}
+
+	if err := sf.Clientset.CoreV1().Secrets(testNamespace).Delete(context.Background(), "web-tls", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	b.Spec.Volumes[1].Secret.SecretName = "web-tls-v2"
+	got, err = sf.diffPods(context.Background(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []Difference
+	for _, d := range got {
+		if d.Section == "References" || d.Field == "volumes/tls" {
+			refs = append(refs, d)
+		}
+	}
+	want = []Difference{
+		{Section: "Spec", Field: "volumes/tls", A: "secret web-tls", B: "secret web-tls-v2"},
+		{Section: "References", Field: "Secret/web-tls", A: "missing"},
+		{Section: "References", Field: "Secret/web-tls-v2", B: "missing"},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("reference differences =\n%v\nwant\n%v", refs, want)
+	}
+}
+
+func TestGetPod(t *testing.T) {
+	elsewhere := testPod()
+	elsewhere.Namespace = "staging"
+	sf, _ := newTestPlugin(testPod(), elsewhere)
+	ctx := context.Background()
+	if pod, err := sf.getPod(ctx, "pod/web-7d4b9-abcde", "staging"); err != nil || pod.Namespace != "staging" {
+		t.Errorf("getPod() = %v, %v", pod, err)
+	}
+	if _, err := sf.getPod(ctx, "web-7d4b9-abcde", ""); err == nil {
+		t.Error("a pod in two namespaces should ask for -n")
+	}
+	if _, err := sf.getPod(ctx, "web", testNamespace); err == nil {
+		t.Error("getPod() should not match a prefix")
+	}
+}
@@ -0,0 +1,237 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// TimelineEvent is an event merged across all its occurrences for one object.
+type TimelineEvent struct {
+	Object    ObjectRef   `json:"object"`
+	FieldPath string      `json:"fieldPath,omitempty"`
+	Type      string      `json:"type"`
+	Reason    string      `json:"reason"`
+	Message   string      `json:"message"`
+	Count     int32       `json:"count"`
+	FirstSeen metav1.Time `json:"firstSeen"`
+	LastSeen  metav1.Time `json:"lastSeen"`
+}
+
+// eventSubjects returns the objects whose events belong to the pod timeline:
+// the pod, its direct owners, its PVCs and its node. Starting from a workload,
+// they are the workload, its pods and their direct owners, and its PVCs.
+func (sf *SnifferPlugin) eventSubjects() []ObjectRef {
+	namespace := sf.PodObject.Namespace
+	var subjects []ObjectRef
+	owners := make(map[ObjectRef]bool)
+	addPod := func(pod *v1.Pod) {
+		subjects = append(subjects, newObjectRef("Pod", pod))
+		for _, own := range pod.GetOwnerReferences() {
+			ref := ObjectRef{Kind: own.Kind, Namespace: namespace, Name: own.Name}
+			if !owners[ref] {
+				owners[ref] = true
+				subjects = append(subjects, ref)
+			}
+		}
+	}
+	if sf.fromWorkload {
+		workload := sf.workloadRef()
+		owners[workload] = true
+		subjects = append(subjects, workload)
+		for i := range sf.AllInfo.Pods {
+			addPod(&sf.AllInfo.Pods[i])
+		}
+	} else {
+		addPod(sf.PodObject)
+	}
+	seen := make(map[string]bool)
+	for _, ref := range sf.AllInfo.References {
+		if ref.Kind == "PersistentVolumeClaim" && !seen[ref.Name] {
+			seen[ref.Name] = true
+			subjects = append(subjects, ObjectRef{Kind: ref.Kind, Namespace: namespace, Name: ref.Name})
+		}
+	}
+	if sf.PodObject.Spec.NodeName != "" {
+		subjects = append(subjects, ObjectRef{Kind: "Node", Name: sf.PodObject.Spec.NodeName})
+	}
+	return subjects
+}
+
+func (sf *SnifferPlugin) findEvents(ctx context.Context) error {
+	subjects := sf.eventSubjects()
+	found := make([][]v1.Event, len(subjects))
+	tasks := make([]task, 0, len(subjects))
+	for i, subject := range subjects {
+		i, subject := i, subject
+		tasks = append(tasks, func(ctx context.Context) error {
+			events, err := sf.objectEvents(ctx, subject)
+			// Node events live in another namespace, which a namespace-scoped
+			// user may not see while the pod events are fine.
+			resource := "events"
+			if subject.Kind == "Node" {
+				resource = "events (node)"
+			}
+			if sf.notVisible(resource, err) {
+				return nil
+			}
+			found[i] = events
+			return err
+		})
+	}
+	if err := runConcurrently(ctx, tasks...); err != nil {
+		return err
+	}
+	var events []v1.Event
+	for _, f := range found {
+		events = append(events, f...)
+	}
+	sf.AllInfo.Events = mergeEvents(events)
+	return nil
+}
+
+// objectEvents returns the events whose involved object is the given one.
+// The kubelet records node events in the default namespace.
+func (sf *SnifferPlugin) objectEvents(ctx context.Context, subject ObjectRef) ([]v1.Event, error) {
+	selector := fields.Set{
+		"involvedObject.kind": subject.Kind,
+		"involvedObject.name": subject.Name,
+	}.AsSelector().String()
+	namespace := subject.Namespace
+	if subject.Kind == "Node" {
+		namespace = v1.NamespaceDefault
+	}
+	eventFind, err := sf.Clientset.CoreV1().Events(namespace).List(
+		ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	var events []v1.Event
+	for _, e := range eventFind.Items {
+		if e.InvolvedObject.Kind == subject.Kind && e.InvolvedObject.Name == subject.Name {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// mergeEvents deduplicates events by object, type, reason and message, summing
+// their counts, and sorts the result chronologically by last occurrence.
+func mergeEvents(events []v1.Event) []TimelineEvent {
+	var timeline []TimelineEvent
+	index := make(map[string]int)
+	for _, e := range events {
+		obj := ObjectRef{Kind: e.InvolvedObject.Kind, Namespace: e.InvolvedObject.Namespace, Name: e.InvolvedObject.Name}
+		first, last, count := eventOccurrence(e)
+		key := obj.String() + "\x00" + e.InvolvedObject.FieldPath + "\x00" + e.Type + "\x00" + e.Reason + "\x00" + e.Message
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(timeline)
+			timeline = append(timeline, TimelineEvent{
+				Object:    obj,
+				FieldPath: e.InvolvedObject.FieldPath,
+				Type:      e.Type,
+				Reason:    e.Reason,
+				Message:   e.Message,
+				Count:     count,
+				FirstSeen: first,
+				LastSeen:  last,
+			})
+			continue
+		}
+		t := &timeline[i]
+		t.Count += count
+		if first.Before(&t.FirstSeen) {
+			t.FirstSeen = first
+		}
+		if t.LastSeen.Before(&last) {
+			t.LastSeen = last
+		}
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		if !timeline[i].LastSeen.Equal(&timeline[j].LastSeen) {
+			return timeline[i].LastSeen.Before(&timeline[j].LastSeen)
+		}
+		return timeline[i].FirstSeen.Before(&timeline[j].FirstSeen)
+	})
+	return timeline
+}
+
+// eventOccurrence normalizes core/v1 events and events.k8s.io style events
+// (EventTime and Series) into first seen, last seen and count.
+func eventOccurrence(e v1.Event) (metav1.Time, metav1.Time, int32) {
+	first, last, count := e.FirstTimestamp, e.LastTimestamp, e.Count
+	if first.IsZero() {
+		first = metav1.NewTime(e.EventTime.Time)
+	}
+	if e.Series != nil {
+		if count == 0 {
+			count = e.Series.Count
+		}
+		if last.IsZero() {
+			last = metav1.NewTime(e.Series.LastObservedTime.Time)
+		}
+	}
+	if last.IsZero() {
+		last = first
+	}
+	if first.IsZero() {
+		first = last
+	}
+	if count == 0 {
+		count = 1
+	}
+	return first, last, count
+}
+
+func (sf *SnifferPlugin) printEvents() {
+	if len(sf.AllInfo.Events) == 0 {
+		return
+	}
+	table := uitable.New()
+	table.Wrap = true
+	table.MaxColWidth = 80
+	table.AddRow("")
+	table.AddRow("LAST SEEN", "FIRST SEEN", "COUNT", "TYPE", "OBJECT", "REASON", "MESSAGE")
+	now := time.Now()
+	for _, e := range sf.AllInfo.Events {
+		eventType := pterm.Green(e.Type)
+		if e.Type != v1.EventTypeNormal {
+			eventType = pterm.Red(e.Type)
+		}
+		table.AddRow(
+			translateAge(e.LastSeen, now),
+			translateAge(e.FirstSeen, now),
+			fmt.Sprint(e.Count),
+			eventType,
+			e.Object.Kind+"/"+e.Object.Name,
+			e.Reason,
+			e.Message)
+	}
+	_, _ = cfmt.Println("{{ Events }}::bgCyan|#ffffff")
+	fmt.Println(table)
+}
+
+func translateAge(t metav1.Time, now time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	d := now.Sub(t.Time)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
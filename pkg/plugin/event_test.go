@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testEvent(name, kind, object, reason string, count int32, first, last time.Time) *v1.Event {
+	return &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		InvolvedObject: v1.ObjectReference{Kind: kind, Name: object, Namespace: testNamespace},
+		Type:           v1.EventTypeNormal,
+		Reason:         reason,
+		Message:        reason + " " + object,
+		Count:          count,
+		FirstTimestamp: metav1.NewTime(first),
+		LastTimestamp:  metav1.NewTime(last),
+	}
+}
+
+func TestFindEvents(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	pod := testPod()
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    []string
+	}{
+		{
+			name: "pod, owner and node sorted by last seen",
+			objects: []runtime.Object{
+				testEvent("e1", "Pod", pod.Name, "Started", 1, at(2), at(2)),
+				testEvent("e2", "ReplicaSet", "web-7d4b9", "SuccessfulCreate", 1, at(1), at(1)),
+				testEvent("e3", "Node", "node-1", "NodeReady", 1, at(0), at(0)),
+			},
+			want: []string{"Node/node-1 NodeReady x1", "ReplicaSet/web-7d4b9 SuccessfulCreate x1", "Pod/web-7d4b9-abcde Started x1"},
+		},
+		{
+			name: "duplicates merged",
+			objects: []runtime.Object{
+				testEvent("e1", "Pod", pod.Name, "BackOff", 3, at(0), at(5)),
+				testEvent("e2", "Pod", pod.Name, "BackOff", 2, at(6), at(9)),
+			},
+			want: []string{"Pod/web-7d4b9-abcde BackOff x5"},
+		},
+		{
+			name: "unrelated objects ignored",
+			objects: []runtime.Object{
+				testEvent("e1", "Pod", "db-0", "Started", 1, at(0), at(0)),
+				testEvent("e2", "Node", "node-2", "NodeReady", 1, at(0), at(0)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(tt.objects...)
+			sf.PodObject = pod
+			if err := sf.findEvents(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range sf.AllInfo.Events {
+				got = append(got, fmt.Sprintf("%s/%s %s x%d", e.Object.Kind, e.Object.Name, e.Reason, e.Count))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindEventsForbidden(t *testing.T) {
+	sf, clientset := newTestPlugin()
+	forbid(clientset, "list", "events")
+	sf.PodObject = testPod()
+	if err := sf.findEvents(context.Background()); err != nil {
+		t.Fatalf("forbidden events should not fail the lookup: %v", err)
+	}
+	got := sf.AllInfo.NotVisible
+	sort.Slice(got, func(i, j int) bool { return got[i].Resource < got[j].Resource })
+	want := []NotVisible{{Resource: "events", Reason: "forbidden"}, {Resource: "events (node)", Reason: "forbidden"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NotVisible = %v, want %v", got, want)
+	}
+}
+
+func TestFindEventsNodeForbidden(t *testing.T) {
+	pod := testPod()
+	pod.Namespace = "shop"
+	event := testEvent("e1", "Pod", pod.Name, "Started", 1, time.Now(), time.Now())
+	event.Namespace, event.InvolvedObject.Namespace = "shop", "shop"
+	sf, clientset := newTestPlugin(event)
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "shop" {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "", nil)
+		}
+		return false, nil, nil
+	})
+	sf.PodObject = pod
+	if err := sf.findEvents(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.AllInfo.Events) != 1 || sf.AllInfo.Events[0].Reason != "Started" {
+		t.Errorf("pod events = %v", sf.AllInfo.Events)
+	}
+	if want := []NotVisible{{Resource: "events (node)", Reason: "forbidden"}}; !reflect.DeepEqual(sf.AllInfo.NotVisible, want) {
+		t.Errorf("NotVisible = %v, want %v", sf.AllInfo.NotVisible, want)
+	}
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// kindColors keeps graph exports visually consistent with the colors used in the tree.
+var kindColors = map[string]string{
+	"Namespace":               "#80deea",
+	"Deployment":              "#90caf9",
+	"StatefulSet":             "#90caf9",
+	"DaemonSet":               "#90caf9",
+	"ReplicaSet":              "#90caf9",
+	"Job":                     "#90caf9",
+	"CronJob":                 "#90caf9",
+	"Node":                    "#ce93d8",
+	"Pod":                     "#a5d6a7",
+	"Service":                 "#fff59d",
+	"Ingress":                 "#c5e1a5",
+	"HTTPRoute":               "#c5e1a5",
+	"GRPCRoute":               "#c5e1a5",
+	"Gateway":                 "#dcedc8",
+	"PersistentVolumeClaim":   "#ffe082",
+	"PersistentVolume":        "#ffcc80",
+	"ConfigMap":               "#f48fb1",
+	"Secret":                  "#ef9a9a",
+	"ServiceAccount":          "#e0e0e0",
+	"HorizontalPodAutoscaler": "#b2ebf2",
+	"PodDisruptionBudget":     "#b2ebf2",
+}
+
+type Graph struct {
+	Nodes []ObjectRef
+	Edges []Relationship
+	// Unhealthy nodes get a red border, Orphans a dashed one.
+	Unhealthy map[ObjectRef]bool
+	Orphans   map[ObjectRef]bool
+}
+
+func newGraph(rels []Relationship) *Graph {
+	g := &Graph{Edges: rels}
+	seen := make(map[ObjectRef]bool)
+	for _, r := range rels {
+		for _, ref := range []ObjectRef{r.From, r.To} {
+			if !seen[ref] {
+				seen[ref] = true
+				g.Nodes = append(g.Nodes, ref)
+			}
+		}
+	}
+	return g
+}
+
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+func (g *Graph) WriteDot(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph \"pod-lens\" {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, n := range g.Nodes {
+		color, ok := kindColors[n.Kind]
+		if !ok {
+			color = "#eeeeee"
+		}
+		var extra string
+		if g.Unhealthy[n] {
+			extra += `, color="#d32f2f", penwidth=2`
+		}
+		if g.Orphans[n] {
+			extra += `, style="rounded,filled,dashed"`
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, fillcolor=%q%s];\n",
+			dotQuote(n.String()), dotQuote(n.Kind+"\n"+n.Name), color, extra)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n",
+			dotQuote(e.From.String()), dotQuote(e.To.String()), dotQuote(e.Type))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	ids := make(map[ObjectRef]string, len(g.Nodes))
+	classes := make(map[string]string)
+	b.WriteString("graph LR\n")
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n] = id
+		class := strings.ToLower(n.Kind)
+		classes[class] = n.Kind
+		fmt.Fprintf(&b, "  %s[\"%s<br/>%s\"]:::%s\n", id, n.Kind, mermaidEscape(n.Name), class)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], mermaidEscape(e.Type), ids[e.To])
+	}
+	for _, n := range g.Nodes {
+		class := strings.ToLower(n.Kind)
+		kind, ok := classes[class]
+		if !ok {
+			continue
+		}
+		delete(classes, class)
+		color, ok := kindColors[kind]
+		if !ok {
+			color = "#eeeeee"
+		}
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#555\n", class, color)
+	}
+	for _, n := range g.Nodes {
+		var style []string
+		if g.Unhealthy[n] {
+			style = append(style, "stroke:#d32f2f", "stroke-width:3px")
+		}
+		if g.Orphans[n] {
+			style = append(style, "stroke-dasharray:5 5")
+		}
+		if len(style) > 0 {
+			fmt.Fprintf(&b, "  style %s %s\n", ids[n], strings.Join(style, ","))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "|", "#124;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
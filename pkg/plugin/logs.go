@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultLogLines is the tail length of a bare --logs.
+	DefaultLogLines = 20
+	// logGrepLines is how far back --logs-grep searches for matching lines.
+	logGrepLines = 1000
+	// logLimitBytes caps a single log request.
+	logLimitBytes = 1 << 20
+	// maxLogLineWidth keeps long lines such as stack dumps or JSON from
+	// stretching the tree.
+	maxLogLineWidth = 120
+)
+
+// ContainerLogs is the tail of one container's log, or of its previous
+// instance when it restarted.
+type ContainerLogs struct {
+	Container string   `json:"container"`
+	Previous  bool     `json:"previous,omitempty"`
+	Lines     []string `json:"lines"`
+	// Omitted counts the lines, or matching lines with --logs-grep, left out
+	// of Lines.
+	Omitted int    `json:"omitted,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// containerLog reads a container log and splits it into lines.
+func (sf *SnifferPlugin) containerLog(ctx context.Context, pod *v1.Pod, opts v1.PodLogOptions) ([]string, error) {
+	limit := int64(logLimitBytes)
+	opts.LimitBytes = &limit
+	raw, err := sf.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &opts).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimRight(string(raw), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// findLogs tails the log of every container of the pod, and the log of the
+// previous instance of the ones that restarted. With grep only matching lines
+// are kept, searched in a longer tail.
+func (sf *SnifferPlugin) findLogs(ctx context.Context, lines int) error {
+	tail := int64(lines)
+	if sf.logGrep != nil {
+		tail = logGrepLines
+	}
+	var statuses []v1.ContainerStatus
+	statuses = append(statuses, sf.PodObject.Status.InitContainerStatuses...)
+	statuses = append(statuses, sf.PodObject.Status.ContainerStatuses...)
+	logs := make([]ContainerLogs, 0, len(statuses))
+	var tasks []task
+	for _, status := range statuses {
+		for _, previous := range []bool{false, true} {
+			if previous && status.RestartCount == 0 {
+				continue
+			}
+			i := len(logs)
+			logs = append(logs, ContainerLogs{Container: status.Name, Previous: previous, Lines: []string{}})
+			opts := v1.PodLogOptions{Container: status.Name, Previous: previous, TailLines: &tail}
+			tasks = append(tasks, func(ctx context.Context) error {
+				found, err := sf.containerLog(ctx, sf.PodObject, opts)
+				if sf.notVisible("pods/log", err) {
+					logs[i].Error, _ = notVisibleReason(err)
+					return nil
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return err
+					}
+					// A container that never started or was never restarted has
+					// no log to show, which should not fail the lens.
+					logs[i].Error = err.Error()
+					return nil
+				}
+				logs[i].Lines, logs[i].Omitted = filterLog(found, lines, sf.logGrep)
+				return nil
+			})
+		}
+	}
+	if err := runConcurrently(ctx, tasks...); err != nil {
+		return errors.Wrap(err, "failed to read container logs")
+	}
+	sf.AllInfo.Logs = logs
+	return nil
+}
+
+// filterLog keeps the last n lines, matching grep when set, and cleans them
+// up for the terminal.
+func filterLog(lines []string, n int, grep *regexp.Regexp) ([]string, int) {
+	kept := []string{}
+	for _, line := range lines {
+		line = pterm.RemoveColorFromString(strings.TrimRight(line, "\r"))
+		if grep == nil || grep.MatchString(line) {
+			kept = append(kept, runewidth.Truncate(strings.ReplaceAll(line, "\t", "    "), maxLogLineWidth, "…"))
+		}
+	}
+	omitted := 0
+	if len(kept) > n {
+		omitted = len(kept) - n
+		kept = kept[omitted:]
+	}
+	return kept, omitted
+}
+
+// logsLeveledList renders the logs of a container under its tree entry.
+func (sf *SnifferPlugin) logsLeveledList(container string) ([]pterm.LeveledListItem, string) {
+	grep := sf.logGrep
+	var leveledList []pterm.LeveledListItem
+	var stateList string
+	for _, l := range sf.AllInfo.Logs {
+		if l.Container != container {
+			continue
+		}
+		label := "logs"
+		if l.Previous {
+			label = "previous logs"
+		}
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 5, Text: pterm.Gray(label)})
+		switch {
+		case l.Error != "":
+			stateList += pterm.Yellow(l.Error) + "\n"
+		case grep != nil:
+			stateList += pterm.Gray(fmt.Sprintf("%d matching %q", len(l.Lines)+l.Omitted, grep.String())) + "\n"
+		default:
+			stateList += pterm.Gray(fmt.Sprintf("last %d lines", len(l.Lines))) + "\n"
+		}
+		if l.Omitted > 0 {
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 6, Text: pterm.Gray(fmt.Sprintf("… %d more", l.Omitted))})
+			stateList += "\n"
+		}
+		for _, line := range l.Lines {
+			if grep != nil {
+				line = grep.ReplaceAllStringFunc(line, func(s string) string { return pterm.Yellow(s) })
+			}
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 6, Text: line})
+			stateList += "\n"
+		}
+	}
+	return leveledList, stateList
+}
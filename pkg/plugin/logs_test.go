@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestFilterLog(t *testing.T) {
+	long := strings.Repeat("x", maxLogLineWidth+10)
+	tests := []struct {
+		name        string
+		lines       []string
+		n           int
+		grep        string
+		want        []string
+		wantOmitted int
+	}{
+		{"tail", []string{"a", "b", "c"}, 2, "", []string{"b", "c"}, 1},
+		{"shorter than tail", []string{"a"}, 5, "", []string{"a"}, 0},
+		{"grep keeps the last matches", []string{"error 1", "ok", "error 2", "error 3"}, 2, "error", []string{"error 2", "error 3"}, 1},
+		{"no match", []string{"ok"}, 5, "panic", []string{}, 0},
+		{"cleanup", []string{"\x1b[31mred\x1b[0m\r", "a\tb"}, 5, "", []string{"red", "a    b"}, 0},
+		{"long line", []string{long}, 5, "", []string{long[:maxLogLineWidth-1] + "…"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var grep *regexp.Regexp
+			if tt.grep != "" {
+				grep = regexp.MustCompile(tt.grep)
+			}
+			got, omitted := filterLog(tt.lines, tt.n, grep)
+			if !reflect.DeepEqual(got, tt.want) || omitted != tt.wantOmitted {
+				t.Errorf("filterLog() = %q, %d, want %q, %d", got, omitted, tt.want, tt.wantOmitted)
+			}
+		})
+	}
+}
+
+func TestFindLogs(t *testing.T) {
+	pod := testPod()
+	pod.Spec.InitContainers = []v1.Container{{Name: "migrate", Image: "migrate"}}
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "sidecar", Image: "envoy"})
+	pod.Status.InitContainerStatuses = []v1.ContainerStatus{{Name: "migrate",
+		State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Completed"}}}}
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{Name: "app", RestartCount: 3, State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+		{Name: "sidecar", Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+	}
+	sf, _ := newTestPlugin(pod)
+	sf.PodObject = pod
+	if err := sf.findLogs(context.Background(), DefaultLogLines); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range sf.AllInfo.Logs {
+		entry := l.Container
+		if l.Previous {
+			entry += " (previous)"
+		}
+		got = append(got, entry+": "+strings.Join(l.Lines, "|"))
+	}
+	want := []string{"migrate: fake logs", "app: fake logs", "app (previous): fake logs", "sidecar: fake logs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logs = %q, want %q", got, want)
+	}
+
+	panel := pterm.RemoveColorFromString(sf.podPanel())
+	container := strings.Index(panel, "[Container]  app")
+	previous := strings.Index(panel, "previous logs")
+	sidecar := strings.Index(panel, "[Container]  sidecar")
+	if container < 0 || previous < container || sidecar < previous {
+		t.Errorf("logs not rendered under their container:\n%s", panel)
+	}
+
+	sf.logGrep = regexp.MustCompile("panic")
+	if err := sf.findLogs(context.Background(), DefaultLogLines); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range sf.AllInfo.Logs {
+		if len(l.Lines) != 0 {
+			t.Errorf("%s kept %q not matching the grep", l.Container, l.Lines)
+		}
+	}
+	if panel = pterm.RemoveColorFromString(sf.podPanel()); !strings.Contains(panel, `0 matching "panic"`) {
+		t.Errorf("grep summary missing:\n%s", panel)
+	}
+}
@@ -0,0 +1,626 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	appsv1 "k8s.io/api/apps/v1"
+	autov1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const namespaceDocumentKind = "PodLensNamespace"
+
+// NamespaceDocument is the result of the namespace map.
+type NamespaceDocument struct {
+	APIVersion    string              `json:"apiVersion"`
+	Kind          string              `json:"kind"`
+	Namespace     string              `json:"namespace"`
+	Workloads     []NamespaceWorkload `json:"workloads"`
+	Relationships []Relationship      `json:"relationships"`
+	Unhealthy     []ObjectRef         `json:"unhealthy"`
+	Dangling      []DanglingObject    `json:"dangling"`
+	Orphans       []ObjectRef         `json:"orphans"`
+	NotVisible    []NotVisible        `json:"notVisible,omitempty"`
+}
+
+// NamespaceWorkload is a workload of the namespace with its pods. Pods without
+// a controller are workloads of their own.
+type NamespaceWorkload struct {
+	Owner     ObjectRef    `json:"owner"`
+	Replicas  string       `json:"replicas,omitempty"`
+	Pods      []PodSummary `json:"pods"`
+	Unhealthy bool         `json:"unhealthy"`
+}
+
+// DanglingObject is referenced by pods of the namespace but is missing, or
+// lacks some of the referenced keys.
+type DanglingObject struct {
+	Object  ObjectRef `json:"object"`
+	Problem string    `json:"problem"`
+}
+
+type namespaceList func(ctx context.Context, c kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error)
+
+// namespaceLists are listed once each; the per-pod lookups then run against
+// an in-memory copy of the results.
+var namespaceLists = map[string]namespaceList{
+	"pods": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Pods(ns).List(ctx, opts)
+	},
+	"replicasets": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.AppsV1().ReplicaSets(ns).List(ctx, opts)
+	},
+	"deployments": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.AppsV1().Deployments(ns).List(ctx, opts)
+	},
+	"statefulsets": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.AppsV1().StatefulSets(ns).List(ctx, opts)
+	},
+	"daemonsets": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.AppsV1().DaemonSets(ns).List(ctx, opts)
+	},
+	"jobs": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.BatchV1().Jobs(ns).List(ctx, opts)
+	},
+	"cronjobs": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.BatchV1().CronJobs(ns).List(ctx, opts)
+	},
+	"services": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Services(ns).List(ctx, opts)
+	},
+	"ingresses": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.NetworkingV1().Ingresses(ns).List(ctx, opts)
+	},
+	"persistentvolumeclaims": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().PersistentVolumeClaims(ns).List(ctx, opts)
+	},
+	"configmaps": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().ConfigMaps(ns).List(ctx, opts)
+	},
+	"secrets": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Secrets(ns).List(ctx, opts)
+	},
+	"serviceaccounts": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().ServiceAccounts(ns).List(ctx, opts)
+	},
+	"horizontalpodautoscalers": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.AutoscalingV1().HorizontalPodAutoscalers(ns).List(ctx, opts)
+	},
+	"poddisruptionbudgets": func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.PolicyV1().PodDisruptionBudgets(ns).List(ctx, opts)
+	},
+}
+
+// namespaceSnapshot holds every object of the namespace, and clients serving
+// them from memory.
+type namespaceSnapshot struct {
+	objects   []runtime.Object
+	clientset *fake.Clientset
+	dynamic   dynamic.Interface
+}
+
+func (sf *SnifferPlugin) snapshotNamespace(ctx context.Context, namespace string) (*namespaceSnapshot, error) {
+	var (
+		mu      sync.Mutex
+		objects []runtime.Object
+		routes  []runtime.Object
+		hidden  = make(map[string]error)
+		tasks   []task
+	)
+	for resource, list := range namespaceLists {
+		resource, list := resource, list
+		tasks = append(tasks, func(ctx context.Context) error {
+			result, err := list(ctx, sf.Clientset, namespace, metav1.ListOptions{})
+			if sf.notVisible(resource, err) {
+				mu.Lock()
+				hidden[resource] = err
+				mu.Unlock()
+				return nil
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to list %s", resource)
+			}
+			items, err := meta.ExtractList(result)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			objects = append(objects, items...)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if sf.DynamicClient != nil {
+		tasks = append(tasks, sf.ifEnabled("routes", func(ctx context.Context) error {
+			for _, rr := range routeResources {
+				for _, version := range rr.Versions {
+					gvr := schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: rr.Resource}
+					routeFind, err := sf.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+					if apierrors.IsNotFound(err) {
+						continue
+					}
+					if sf.notVisible(rr.Resource, err) {
+						break
+					}
+					if err != nil {
+						return err
+					}
+					mu.Lock()
+					for i := range routeFind.Items {
+						routes = append(routes, &routeFind.Items[i])
+					}
+					mu.Unlock()
+					break
+				}
+			}
+			return nil
+		}))
+	}
+	if err := runConcurrently(ctx, tasks...); err != nil {
+		return nil, err
+	}
+
+	snapshot := &namespaceSnapshot{objects: objects, clientset: newSnapshotClientset(objects, hidden)}
+	if sf.DynamicClient != nil {
+		snapshot.dynamic = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), routeListKinds(), routes...)
+	}
+	return snapshot, nil
+}
+
+// newSnapshotClientset serves the objects from memory. The hidden resources
+// keep failing with the error seen when listing them, so they are reported as
+// not visible rather than missing.
+//
+// Label selectors are applied but field selectors are ignored, so a List with
+// a field selector returns every object of the namespace. Every lookup that
+// may run against a snapshot must re-check on the client side what its field
+// selector asks for, as findWorkload, objectEvents and findNodeLens do.
+func newSnapshotClientset(objects []runtime.Object, hidden map[string]error) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	for resource, err := range hidden {
+		err := err
+		clientset.PrependReactor("*", resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, err
+		})
+	}
+	return clientset
+}
+
+// findNamespaceLens runs the relationship discovery for every pod of the
+// namespace and merges the results into one graph of its workloads.
+func (sf *SnifferPlugin) findNamespaceLens(ctx context.Context, namespace string, opts Options) (*NamespaceDocument, error) {
+	snapshot, err := sf.snapshotNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshot.objects) == 0 && len(sf.AllInfo.NotVisible) == 0 {
+		return nil, errors.Errorf("no objects found in namespace %q, please check your parameters, set a context or verify API server.", namespace)
+	}
+
+	var pods []v1.Pod
+	for _, obj := range snapshot.objects {
+		if pod, ok := obj.(*v1.Pod); ok {
+			pods = append(pods, *pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	owners, err := NewSnifferPluginForClients(snapshot.clientset, nil).workloadOwners(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	podOwners := make(map[ObjectRef]ObjectRef, len(pods))
+	for i := range pods {
+		podOwners[newObjectRef("Pod", &pods[i])] = podOwner(&pods[i], owners)
+	}
+	collapse := func(ref ObjectRef) ObjectRef {
+		if owner, ok := podOwners[ref]; ok {
+			return owner
+		}
+		if owner, ok := owners[ref]; ok {
+			return owner
+		}
+		return ref
+	}
+
+	// Events, the node and the scheduling analysis say nothing about how the
+	// objects of the namespace relate to each other.
+	resources := sf.withoutEvents()
+
+	doc := &NamespaceDocument{
+		APIVersion: documentAPIVersion,
+		Kind:       namespaceDocumentKind,
+		Namespace:  namespace,
+		Workloads:  []NamespaceWorkload{},
+		Unhealthy:  []ObjectRef{},
+		Dangling:   []DanglingObject{},
+		Orphans:    []ObjectRef{},
+	}
+	seen := make(map[Relationship]bool)
+	add := func(rel Relationship) {
+		if rel.From != rel.To && !seen[rel] {
+			seen[rel] = true
+			doc.Relationships = append(doc.Relationships, rel)
+		}
+	}
+	workloads := make(map[ObjectRef]int)
+	dangling := make(map[DanglingObject]bool)
+	for i := range pods {
+		sub := NewSnifferPluginForClients(snapshot.clientset, snapshot.dynamic)
+		sub.resources = resources
+		sub.graphOnly = true
+		sub.PodObject = &pods[i]
+		if err = sub.getLabelByPod("", opts.LabelKeys); err != nil {
+			return nil, err
+		}
+		if err = sub.findRelated(ctx, opts); err != nil {
+			return nil, errors.Wrapf(err, "pod %s", pods[i].Name)
+		}
+		for _, rel := range sub.buildRelationships() {
+			if rel.From.Kind != "Node" && rel.To.Kind != "Node" {
+				add(Relationship{From: collapse(rel.From), To: collapse(rel.To), Type: rel.Type})
+			}
+		}
+		for _, ref := range sub.danglingReferences() {
+			d := DanglingObject{Object: ObjectRef{Kind: ref.Kind, Namespace: namespace, Name: ref.Name}, Problem: ref.Problem()}
+			if !dangling[d] {
+				dangling[d] = true
+				doc.Dangling = append(doc.Dangling, d)
+			}
+		}
+
+		owner := podOwners[newObjectRef("Pod", &pods[i])]
+		w, ok := workloads[owner]
+		if !ok {
+			w = len(doc.Workloads)
+			workloads[owner] = w
+			doc.Workloads = append(doc.Workloads, NamespaceWorkload{Owner: owner})
+			if sub.AllInfo.Workload.Name == owner.Name && sub.AllInfo.Workload.Replicas != "0" {
+				doc.Workloads[w].Replicas = sub.AllInfo.Workload.Replicas
+			}
+		}
+		summary := summarizePod(&pods[i])
+		doc.Workloads[w].Pods = append(doc.Workloads[w].Pods, summary)
+		doc.Workloads[w].Unhealthy = doc.Workloads[w].Unhealthy || summary.Unhealthy || sub.AllInfo.Workload.Status
+	}
+
+	// Workloads scaled to zero or waiting for their next run have no pods but
+	// still belong on the map.
+	nsRef := ObjectRef{Kind: "Namespace", Name: namespace}
+	addIdle := func(ref ObjectRef, replicas string, unhealthy bool) {
+		if w, ok := workloads[ref]; ok {
+			if doc.Workloads[w].Replicas == "" {
+				doc.Workloads[w].Replicas = replicas
+			}
+			return
+		}
+		workloads[ref] = len(doc.Workloads)
+		doc.Workloads = append(doc.Workloads, NamespaceWorkload{Owner: ref, Replicas: replicas, Pods: []PodSummary{}, Unhealthy: unhealthy})
+		add(Relationship{From: nsRef, To: ref, Type: "contains"})
+	}
+	for _, obj := range snapshot.objects {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			addIdle(newObjectRef("Deployment", o), fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, o.Status.Replicas),
+				o.Status.ReadyReplicas != o.Status.Replicas)
+		case *appsv1.StatefulSet:
+			addIdle(newObjectRef("StatefulSet", o), fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, o.Status.Replicas),
+				o.Status.ReadyReplicas != o.Status.Replicas)
+		case *appsv1.DaemonSet:
+			addIdle(newObjectRef("DaemonSet", o), fmt.Sprintf("%d/%d", o.Status.NumberReady, o.Status.DesiredNumberScheduled),
+				o.Status.NumberReady != o.Status.DesiredNumberScheduled)
+		case *batchv1.CronJob:
+			addIdle(newObjectRef("CronJob", o), fmt.Sprintf("%d active", len(o.Status.Active)), false)
+		case *batchv1.Job:
+			if _, owned := owners[newObjectRef("Job", o)]; !owned {
+				addIdle(newObjectRef("Job", o), "", o.Status.Failed > 0)
+			}
+		}
+	}
+	sort.Slice(doc.Workloads, func(i, j int) bool {
+		a, b := doc.Workloads[i].Owner, doc.Workloads[j].Owner
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	nodes := make(map[ObjectRef]bool)
+	for _, rel := range doc.Relationships {
+		nodes[rel.From], nodes[rel.To] = true, true
+	}
+	for _, obj := range snapshot.objects {
+		switch o := obj.(type) {
+		case *netv1.Ingress:
+			if ref := newObjectRef("Ingress", o); nodes[ref] {
+				for _, tls := range o.Spec.TLS {
+					if tls.SecretName != "" {
+						add(Relationship{From: ref, To: ObjectRef{Kind: "Secret", Namespace: namespace, Name: tls.SecretName}, Type: "terminates-tls"})
+					}
+				}
+			}
+		case *autov1.HorizontalPodAutoscaler:
+			target := ObjectRef{Kind: o.Spec.ScaleTargetRef.Kind, Namespace: namespace, Name: o.Spec.ScaleTargetRef.Name}
+			if _, ok := workloads[target]; ok {
+				add(Relationship{From: newObjectRef("HorizontalPodAutoscaler", o), To: target, Type: "scales"})
+			}
+		}
+	}
+	for _, rel := range doc.Relationships {
+		nodes[rel.From], nodes[rel.To] = true, true
+	}
+
+	for _, w := range doc.Workloads {
+		if w.Unhealthy {
+			doc.Unhealthy = append(doc.Unhealthy, w.Owner)
+		}
+	}
+	for _, obj := range snapshot.objects {
+		var ref ObjectRef
+		switch o := obj.(type) {
+		case *v1.PersistentVolumeClaim:
+			ref = newObjectRef("PersistentVolumeClaim", o)
+			if o.Status.Phase != v1.ClaimBound {
+				doc.Unhealthy = append(doc.Unhealthy, ref)
+			}
+		case *v1.Service:
+			// Services without a selector front endpoints managed elsewhere.
+			if len(o.Spec.Selector) == 0 {
+				continue
+			}
+			ref = newObjectRef("Service", o)
+		case *netv1.Ingress:
+			ref = newObjectRef("Ingress", o)
+		case *v1.ConfigMap:
+			if o.Name == "kube-root-ca.crt" {
+				continue
+			}
+			ref = newObjectRef("ConfigMap", o)
+		case *v1.Secret:
+			// Token and Helm release secrets are not meant to be used by pods.
+			if o.Type == v1.SecretTypeServiceAccountToken || o.Type == "helm.sh/release.v1" {
+				continue
+			}
+			ref = newObjectRef("Secret", o)
+		case *autov1.HorizontalPodAutoscaler:
+			ref = newObjectRef("HorizontalPodAutoscaler", o)
+		case *policyv1.PodDisruptionBudget:
+			ref = newObjectRef("PodDisruptionBudget", o)
+		default:
+			continue
+		}
+		if !nodes[ref] {
+			doc.Orphans = append(doc.Orphans, ref)
+		}
+	}
+	sortRefs(doc.Unhealthy)
+	sort.Slice(doc.Dangling, func(i, j int) bool {
+		a, b := doc.Dangling[i], doc.Dangling[j]
+		if a.Object != b.Object {
+			return a.Object.String() < b.Object.String()
+		}
+		return a.Problem < b.Problem
+	})
+	sortRefs(doc.Orphans)
+	sort.SliceStable(doc.Relationships, func(i, j int) bool {
+		a, b := doc.Relationships[i], doc.Relationships[j]
+		if a.From != b.From {
+			return a.From.String() < b.From.String()
+		}
+		if a.To != b.To {
+			return a.To.String() < b.To.String()
+		}
+		return a.Type < b.Type
+	})
+	doc.NotVisible = sf.AllInfo.NotVisible
+	return doc, nil
+}
+
+// withoutEvents returns the enabled resource types except events.
+func (sf *SnifferPlugin) withoutEvents() map[string]bool {
+	resources := make(map[string]bool)
+	for _, r := range ResourceTypes {
+		if r != "events" && sf.enabled(r) {
+			resources[r] = true
+		}
+	}
+	return resources
+}
+
+func sortRefs(refs []ObjectRef) {
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+}
+
+func newNamespaceGraph(doc *NamespaceDocument) *Graph {
+	g := newGraph(doc.Relationships)
+	g.Unhealthy = make(map[ObjectRef]bool)
+	g.Orphans = make(map[ObjectRef]bool)
+	for _, ref := range doc.Unhealthy {
+		g.Unhealthy[ref] = true
+	}
+	for _, d := range doc.Dangling {
+		g.Unhealthy[d.Object] = true
+	}
+	for _, ref := range doc.Orphans {
+		g.Orphans[ref] = true
+		g.Nodes = append(g.Nodes, ref)
+	}
+	return g
+}
+
+func writeNamespaceOutput(w io.Writer, output string, doc *NamespaceDocument) error {
+	switch output {
+	case "dot":
+		return newNamespaceGraph(doc).WriteDot(w)
+	case "mermaid":
+		return newNamespaceGraph(doc).WriteMermaid(w)
+	}
+	return writeDocument(w, output, doc)
+}
+
+var kindStyles = map[string]string{
+	"Service":                 "lightYellow",
+	"Ingress":                 "green",
+	"HTTPRoute":               "green",
+	"GRPCRoute":               "green",
+	"Gateway":                 "green",
+	"PersistentVolumeClaim":   "yellow",
+	"PersistentVolume":        "yellow",
+	"ConfigMap":               "lightMagenta",
+	"Secret":                  "red",
+	"ServiceAccount":          "gray",
+	"HorizontalPodAutoscaler": "lightCyan",
+	"PodDisruptionBudget":     "lightCyan",
+}
+
+func printNamespaceLens(doc *NamespaceDocument) {
+	var pods int
+	for _, w := range doc.Workloads {
+		pods += len(w.Pods)
+	}
+	_, _ = cfmt.Printf("{{ [Namespace] }}::cyan|bold %s Workloads: %d Pods: %d\n\n", doc.Namespace, len(doc.Workloads), pods)
+
+	unhealthy := make(map[ObjectRef]bool)
+	for _, ref := range doc.Unhealthy {
+		unhealthy[ref] = true
+	}
+	for _, d := range doc.Dangling {
+		unhealthy[d.Object] = true
+	}
+	workloads := make(map[ObjectRef]bool)
+	for _, w := range doc.Workloads {
+		workloads[w.Owner] = true
+	}
+	label := func(ref ObjectRef) string {
+		style, ok := kindStyles[ref.Kind]
+		if !ok {
+			style = "lightBlue"
+		}
+		name := ref.Name
+		if unhealthy[ref] {
+			name = pterm.Red(name)
+		}
+		return cfmt.Sprintf("{{ [%s] }}::%s|bold %s", ref.Kind, style, name)
+	}
+	// neighbours returns the other end and type of the edges touching ref,
+	// skipping the namespace and the given objects.
+	neighbours := func(ref ObjectRef, skip func(ObjectRef) bool) []Relationship {
+		var out []Relationship
+		for _, rel := range doc.Relationships {
+			other := rel.To
+			if rel.To == ref {
+				other = rel.From
+			} else if rel.From != ref {
+				continue
+			}
+			if other.Kind != "Namespace" && !skip(other) {
+				out = append(out, Relationship{From: ref, To: other, Type: rel.Type})
+			}
+		}
+		return out
+	}
+
+	var leveledList pterm.LeveledList
+	for _, w := range doc.Workloads {
+		text := label(w.Owner)
+		if w.Replicas != "" {
+			replicas := pterm.Green(w.Replicas)
+			if w.Unhealthy {
+				replicas = pterm.Red(w.Replicas)
+			}
+			text += " Replica: " + replicas
+		}
+		if len(w.Pods) > 0 {
+			text += " Pods: " + podStateCounts(w.Pods)
+		}
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 0, Text: text})
+		for _, rel := range neighbours(w.Owner, func(ref ObjectRef) bool { return workloads[ref] }) {
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
+				Text: label(rel.To) + pterm.Gray(" "+rel.Type)})
+			for _, next := range neighbours(rel.To, func(ref ObjectRef) bool { return workloads[ref] || ref == w.Owner }) {
+				leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
+					Text: label(next.To) + pterm.Gray(" "+next.Type)})
+			}
+		}
+	}
+	_, _ = cfmt.Println("{{ Workloads }}::bgCyan|#ffffff")
+	if len(leveledList) > 0 {
+		root := pterm.NewTreeFromLeveledList(leveledList)
+		root.Text = ""
+		_ = pterm.DefaultTree.WithRoot(root).Render()
+	} else {
+		fmt.Println()
+	}
+
+	if len(doc.Dangling) > 0 {
+		table := uitable.New()
+		table.AddRow("")
+		for _, d := range doc.Dangling {
+			table.AddRow(d.Object.Kind, d.Object.Name, pterm.Red(d.Problem))
+		}
+		_, _ = cfmt.Println("{{ Dangling References }}::bgRed|#ffffff")
+		fmt.Println(table)
+	}
+
+	_, _ = cfmt.Println("{{ Orphans }}::bgYellow|#000000")
+	if len(doc.Orphans) == 0 {
+		fmt.Println(pterm.Green("Every Service, Ingress, PVC, ConfigMap, Secret, HPA and PDB is used.\n"))
+		return
+	}
+	table := uitable.New()
+	table.AddRow("")
+	for _, ref := range doc.Orphans {
+		table.AddRow(ref.Kind, pterm.Yellow(ref.Name))
+	}
+	fmt.Println(table)
+}
+
+func (sf *SnifferPlugin) runNamespaceLens(ctx context.Context, timeout time.Duration, name string, opts Options) error {
+	if opts.Report != "" || opts.CheckAccess || opts.AllNamespaces || opts.Watch || opts.TUI {
+		return errors.New("--report, --check-access, --all-namespaces, --watch and --tui are not supported for ns/<name>")
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	doc, err := sf.findNamespaceLens(ctx, name, opts)
+	if err != nil {
+		return contextError(ctx, timeout, err)
+	}
+	if opts.Output != "" {
+		if err = writeNamespaceOutput(os.Stdout, opts.Output, doc); err != nil {
+			return err
+		}
+	} else {
+		printNamespaceLens(doc)
+		sf.printNotVisible()
+	}
+	if opts.Strict && len(doc.Dangling) > 0 {
+		return errors.Errorf("found %d dangling reference(s)", len(doc.Dangling))
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autov1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testNamespaceCluster() []runtime.Object {
+	debug := testPod()
+	debug.Name, debug.Labels, debug.OwnerReferences = "debug", nil, nil
+	debug.Spec.Containers[0].EnvFrom = []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{
+		LocalObjectReference: v1.LocalObjectReference{Name: "gone"}}}}
+	debug.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "app", Ready: true}}
+	elsewhere := testPod()
+	elsewhere.Namespace = "staging"
+
+	return append(testCluster(),
+		debug, elsewhere,
+		&appsv1.Deployment{ObjectMeta: objectMeta("idle", nil)},
+		&netv1.Ingress{ObjectMeta: objectMeta("web", nil), Spec: netv1.IngressSpec{
+			TLS:            []netv1.IngressTLS{{SecretName: "web-tls"}},
+			DefaultBackend: &netv1.IngressBackend{Service: &netv1.IngressServiceBackend{Name: "web"}},
+		}},
+		&autov1.HorizontalPodAutoscaler{ObjectMeta: objectMeta("web", nil), Spec: autov1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autov1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"}}},
+		&policyv1.PodDisruptionBudget{ObjectMeta: objectMeta("stale", nil), Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "gone"}}}},
+		&v1.ConfigMap{ObjectMeta: objectMeta("old-config", nil)},
+		&v1.ConfigMap{ObjectMeta: objectMeta("kube-root-ca.crt", nil)},
+		&v1.Secret{ObjectMeta: objectMeta("default-token", nil), Type: v1.SecretTypeServiceAccountToken},
+		&v1.PersistentVolumeClaim{ObjectMeta: objectMeta("data", nil), Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimPending}},
+	)
+}
+
+func TestFindNamespaceLens(t *testing.T) {
+	sf, _ := newTestPlugin(testNamespaceCluster()...)
+	doc, err := sf.findNamespaceLens(context.Background(), testNamespace, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := func(kind, name string) ObjectRef {
+		return ObjectRef{Kind: kind, Namespace: testNamespace, Name: name}
+	}
+
+	var workloads []string
+	for _, w := range doc.Workloads {
+		var pods []string
+		for _, p := range w.Pods {
+			pods = append(pods, p.Name)
+		}
+		workloads = append(workloads, w.Owner.String()+" "+w.Replicas+" "+strings.Join(pods, ","))
+	}
+	wantWorkloads := []string{"Deployment/default/idle 0/0 ", "Deployment/default/web 1/1 web-7d4b9-abcde", "Pod/default/debug  debug"}
+	if !reflect.DeepEqual(workloads, wantWorkloads) {
+		t.Errorf("Workloads = %q, want %q", workloads, wantWorkloads)
+	}
+
+	for _, want := range []Relationship{
+		{From: ref("Service", "web"), To: ref("Deployment", "web"), Type: "selects"},
+		{From: ref("Ingress", "web"), To: ref("Service", "web"), Type: "routes-to"},
+		{From: ref("Ingress", "web"), To: ref("Secret", "web-tls"), Type: "terminates-tls"},
+		{From: ref("HorizontalPodAutoscaler", "web"), To: ref("Deployment", "web"), Type: "scales"},
+		{From: ref("Deployment", "web"), To: ref("ConfigMap", "web-config"), Type: "mounts"},
+		{From: ref("Pod", "debug"), To: ref("Secret", "gone"), Type: "envFrom"},
+		{From: ObjectRef{Kind: "Namespace", Name: testNamespace}, To: ref("Deployment", "idle"), Type: "contains"},
+	} {
+		found := false
+		for _, rel := range doc.Relationships {
+			found = found || rel == want
+		}
+		if !found {
+			t.Errorf("missing relationship %+v", want)
+		}
+	}
+	for _, rel := range doc.Relationships {
+		if rel.From.Kind == "Node" || rel.To.Kind == "Node" || rel.To.Name == "web-7d4b9-abcde" {
+			t.Errorf("unexpected relationship %+v", rel)
+		}
+	}
+
+	if want := []ObjectRef{ref("Deployment", "web"), ref("PersistentVolumeClaim", "data")}; !reflect.DeepEqual(doc.Unhealthy, want) {
+		t.Errorf("Unhealthy = %v, want %v", doc.Unhealthy, want)
+	}
+	if want := []DanglingObject{{Object: ref("Secret", "gone"), Problem: "Secret not found"}}; !reflect.DeepEqual(doc.Dangling, want) {
+		t.Errorf("Dangling = %v, want %v", doc.Dangling, want)
+	}
+	wantOrphans := []ObjectRef{ref("ConfigMap", "old-config"), ref("PersistentVolumeClaim", "data"), ref("PodDisruptionBudget", "stale")}
+	if !reflect.DeepEqual(doc.Orphans, wantOrphans) {
+		t.Errorf("Orphans = %v, want %v", doc.Orphans, wantOrphans)
+	}
+
+	var buf bytes.Buffer
+	if err = writeNamespaceOutput(&buf, "dot", doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, `  "PodDisruptionBudget/default/stale" [`) && !strings.Contains(line, "dashed"),
+			strings.HasPrefix(line, `  "Secret/default/gone" [`) && !strings.Contains(line, "#d32f2f"):
+			t.Errorf("node not highlighted: %s", line)
+		}
+	}
+}
+
+func TestFindNamespaceLensNotVisible(t *testing.T) {
+	sf, clientset := newTestPlugin(testNamespaceCluster()...)
+	forbid(clientset, "list", "secrets")
+	doc, err := sf.findNamespaceLens(context.Background(), testNamespace, Options{})
+	if err != nil {
+		t.Fatalf("forbidden secrets should not fail the namespace map: %v", err)
+	}
+	if want := []NotVisible{{Resource: "secrets", Reason: "forbidden"}}; !reflect.DeepEqual(doc.NotVisible, want) {
+		t.Errorf("NotVisible = %v, want %v", doc.NotVisible, want)
+	}
+	if len(doc.Dangling) != 0 {
+		t.Errorf("secrets that cannot be read should not be reported as dangling: %v", doc.Dangling)
+	}
+
+	sf, _ = newTestPlugin()
+	if _, err = sf.findNamespaceLens(context.Background(), "empty", Options{}); err == nil {
+		t.Error("an empty namespace should fail")
+	}
+}
+
+func TestSnapshotClientsetFieldSelectors(t *testing.T) {
+	pod := testPod()
+	other, done := testPod(), testPod()
+	other.Name, other.Spec.NodeName = "web-7d4b9-fghij", "node-2"
+	done.Name, done.Status.Phase = "web-7d4b9-done", v1.PodSucceeded
+	deploy := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: objectMeta(name, nil), Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: v1.PodTemplateSpec{Spec: pod.Spec}}}
+	}
+	event := func(name, pod string) *v1.Event {
+		return &v1.Event{ObjectMeta: objectMeta(name, nil), Reason: "BackOff",
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: testNamespace, Name: pod}}
+	}
+	objects := []runtime.Object{pod, other, done, deploy("web"), deploy("web-canary"),
+		event("a", pod.Name), event("b", other.Name), &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	sf := NewSnifferPluginForClients(newSnapshotClientset(objects, nil), nil)
+	ctx := context.Background()
+
+	// The premise: a snapshot ignores field selectors.
+	list, err := sf.Clientset.AppsV1().Deployments(testNamespace).List(ctx, metav1.ListOptions{FieldSelector: "metadata.name=web"})
+	if err != nil || len(list.Items) != 2 {
+		t.Fatalf("snapshot list = %v, %v, want both deployments", list, err)
+	}
+
+	if err = sf.findWorkload(ctx, "Deployment", "web", testNamespace); err != nil {
+		t.Errorf("findWorkload() = %v", err)
+	}
+	events, err := sf.objectEvents(ctx, newObjectRef("Pod", pod))
+	if err != nil || len(events) != 1 || events[0].Name != "a" {
+		t.Errorf("objectEvents() = %d events, %v, want event a", len(events), err)
+	}
+	if _, err = sf.findNodeLens(ctx, "node-1"); err != nil {
+		t.Fatal(err)
+	}
+	if pods := sf.AllInfo.Pods; len(pods) != 1 || pods[0].Name != pod.Name {
+		t.Errorf("node pods = %+v, want %s", pods, pod.Name)
+	}
+}
@@ -0,0 +1,417 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const nodeDocumentKind = "PodLensNode"
+
+// NodeDocument is the result of the node lens.
+type NodeDocument struct {
+	APIVersion    string         `json:"apiVersion"`
+	Kind          string         `json:"kind"`
+	Node          *v1.Node       `json:"node"`
+	Resources     []NodeResource `json:"resources"`
+	PodGroups     []NodePodGroup `json:"podGroups"`
+	DrainBlockers []DrainBlocker `json:"drainBlockers"`
+	Relationships []Relationship `json:"relationships"`
+	NotVisible    []NotVisible   `json:"notVisible,omitempty"`
+}
+
+// NodeResource compares what the node offers with what its pods ask for.
+type NodeResource struct {
+	Name            string `json:"name"`
+	Capacity        string `json:"capacity"`
+	Allocatable     string `json:"allocatable"`
+	Requests        string `json:"requests"`
+	Limits          string `json:"limits"`
+	RequestsPercent int64  `json:"requestsPercent"`
+	LimitsPercent   int64  `json:"limitsPercent"`
+}
+
+// NodePodGroup holds the pods on the node owned by one workload.
+type NodePodGroup struct {
+	Namespace string       `json:"namespace"`
+	Owner     ObjectRef    `json:"owner"`
+	Pods      []PodSummary `json:"pods"`
+}
+
+// DrainBlocker is a PodDisruptionBudget that would refuse to evict some of
+// the pods on the node.
+type DrainBlocker struct {
+	PodDisruptionBudget ObjectRef `json:"podDisruptionBudget"`
+	DisruptionsAllowed  int32     `json:"disruptionsAllowed"`
+	Pods                []string  `json:"pods"`
+}
+
+var nodeResourceOrder = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage, v1.ResourcePods}
+
+// findNodeLens gathers the node, the pods scheduled on it and the PDBs
+// covering them.
+func (sf *SnifferPlugin) findNodeLens(ctx context.Context, name string) (*NodeDocument, error) {
+	node, err := sf.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, errors.Errorf("Failed to get node: [%s], please check your parameters, set a context or verify API server.", name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get node")
+	}
+	sf.AllInfo.Node = node
+
+	var pods []v1.Pod
+	podList, err := sf.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String()})
+	if !sf.notVisible("pods", err) {
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list pods")
+		}
+		for _, pod := range podList.Items {
+			if pod.Spec.NodeName == name && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+				pods = append(pods, pod)
+			}
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	sf.AllInfo.Pods = pods
+
+	doc := &NodeDocument{
+		APIVersion:    documentAPIVersion,
+		Kind:          nodeDocumentKind,
+		Node:          node.DeepCopy(),
+		Resources:     nodeResources(node, pods),
+		PodGroups:     []NodePodGroup{},
+		DrainBlockers: []DrainBlocker{},
+	}
+
+	owners, err := sf.workloadOwners(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[ObjectRef]int)
+	for i := range pods {
+		owner := podOwner(&pods[i], owners)
+		g, ok := index[owner]
+		if !ok {
+			g = len(doc.PodGroups)
+			index[owner] = g
+			doc.PodGroups = append(doc.PodGroups, NodePodGroup{Namespace: pods[i].Namespace, Owner: owner})
+		}
+		doc.PodGroups[g].Pods = append(doc.PodGroups[g].Pods, summarizePod(&pods[i]))
+		doc.Relationships = append(doc.Relationships,
+			Relationship{From: newObjectRef("Pod", &pods[i]), To: newObjectRef("Node", node), Type: "scheduled-on"})
+		if owner.Kind != "Pod" {
+			doc.Relationships = append(doc.Relationships,
+				Relationship{From: owner, To: newObjectRef("Pod", &pods[i]), Type: "manages"})
+		}
+	}
+
+	if doc.DrainBlockers, err = sf.drainBlockers(ctx, pods); err != nil {
+		return nil, err
+	}
+	for _, b := range doc.DrainBlockers {
+		for _, pod := range b.Pods {
+			doc.Relationships = append(doc.Relationships, Relationship{From: b.PodDisruptionBudget,
+				To: ObjectRef{Kind: "Pod", Namespace: b.PodDisruptionBudget.Namespace, Name: pod}, Type: "protects"})
+		}
+	}
+	doc.NotVisible = sf.AllInfo.NotVisible
+	return doc, nil
+}
+
+func nodeResources(node *v1.Node, pods []v1.Pod) []NodeResource {
+	requests, limits := v1.ResourceList{}, v1.ResourceList{}
+	for i := range pods {
+		requests = addResourceList(requests, podRequests(&pods[i]))
+		limits = addResourceList(limits, podLimits(&pods[i]))
+	}
+
+	names := append([]v1.ResourceName{}, nodeResourceOrder...)
+	var extra []string
+	for name := range node.Status.Allocatable {
+		known := false
+		for _, n := range nodeResourceOrder {
+			known = known || n == name
+		}
+		if !known {
+			extra = append(extra, string(name))
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		names = append(names, v1.ResourceName(name))
+	}
+
+	var resources []NodeResource
+	for _, name := range names {
+		allocatable, ok := node.Status.Allocatable[name]
+		if !ok {
+			continue
+		}
+		capacity := node.Status.Capacity[name]
+		r := NodeResource{
+			Name:        string(name),
+			Capacity:    capacity.String(),
+			Allocatable: allocatable.String(),
+			Requests:    "0",
+			Limits:      "0",
+		}
+		if q, ok := requests[name]; ok {
+			r.Requests = q.String()
+			r.RequestsPercent = percent(q.MilliValue(), allocatable.MilliValue())
+		}
+		if name == v1.ResourcePods {
+			r.Limits = "-"
+		} else if q, ok := limits[name]; ok {
+			r.Limits = q.String()
+			r.LimitsPercent = percent(q.MilliValue(), allocatable.MilliValue())
+		}
+		resources = append(resources, r)
+	}
+	return resources
+}
+
+func percent(value, total int64) int64 {
+	if total == 0 {
+		return 0
+	}
+	return value * 100 / total
+}
+
+// drainBlockers returns the PDBs that allow fewer disruptions than the number
+// of their pods on the node, so draining it would have to wait for them.
+func (sf *SnifferPlugin) drainBlockers(ctx context.Context, pods []v1.Pod) ([]DrainBlocker, error) {
+	blockers := []DrainBlocker{}
+	pdbList, err := sf.Clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if sf.notVisible("poddisruptionbudgets", err) {
+		return blockers, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pod disruption budgets")
+	}
+	for _, pdb := range pdbList.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		var covered []string
+		for _, pod := range pods {
+			if pod.Namespace == pdb.Namespace && evictedByDrain(&pod) && !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+				covered = append(covered, pod.Name)
+			}
+		}
+		if len(covered) > 0 && pdb.Status.DisruptionsAllowed < int32(len(covered)) {
+			blockers = append(blockers, DrainBlocker{
+				PodDisruptionBudget: newObjectRef("PodDisruptionBudget", &pdb),
+				DisruptionsAllowed:  pdb.Status.DisruptionsAllowed,
+				Pods:                covered,
+			})
+		}
+	}
+	return blockers, nil
+}
+
+// evictedByDrain reports whether `kubectl drain` evicts the pod; DaemonSet
+// and static pods are left in place.
+func evictedByDrain(pod *v1.Pod) bool {
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	for _, own := range pod.OwnerReferences {
+		if own.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+func writeNodeOutput(w io.Writer, output string, doc *NodeDocument) error {
+	switch output {
+	case "dot":
+		return newGraph(doc.Relationships).WriteDot(w)
+	case "mermaid":
+		return newGraph(doc.Relationships).WriteMermaid(w)
+	}
+	return writeDocument(w, output, doc)
+}
+
+func nodeReady(node *v1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func printNodeLens(doc *NodeDocument) {
+	node := doc.Node
+	state := pterm.Green("[Ready]")
+	if !nodeReady(node) {
+		state = pterm.Red("[NotReady]")
+	}
+	if node.Spec.Unschedulable {
+		state += pterm.Yellow(" [SchedulingDisabled]")
+	}
+	var address string
+	for _, ip := range node.Status.Addresses {
+		if ip.Type == v1.NodeInternalIP {
+			address = cfmt.Sprintf(" Node IP: {{%s}}::magenta", ip.Address)
+		}
+	}
+	_, _ = cfmt.Printf("{{ [Node] }}::magenta|bold %s %s%s\n", node.Name, pterm.Bold.Sprint(state), address)
+
+	table := uitable.New()
+	table.Wrap = true
+	table.MaxColWidth = 80
+	table.AddRow("")
+	table.AddRow("TYPE", "STATUS", "REASON", "MESSAGE")
+	for _, c := range node.Status.Conditions {
+		status := pterm.Green(string(c.Status))
+		if (c.Type == v1.NodeReady) != (c.Status == v1.ConditionTrue) {
+			status = pterm.Red(string(c.Status))
+		}
+		table.AddRow(string(c.Type), status, c.Reason, c.Message)
+	}
+	_, _ = cfmt.Println("{{ Conditions }}::bgMagenta|#ffffff")
+	fmt.Println(table)
+
+	table = uitable.New()
+	table.Wrap = true
+	table.AddRow("")
+	for _, t := range node.Spec.Taints {
+		taint := t.Key
+		if t.Value != "" {
+			taint += "=" + t.Value
+		}
+		table.AddRow("Taint:", pterm.Yellow(taint+":"+string(t.Effect)))
+	}
+	keys := make([]string, 0, len(node.Labels))
+	for k := range node.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		table.AddRow("Label:", cfmt.Sprintf("%s={{%s}}::cyan", k, node.Labels[k]))
+	}
+	_, _ = cfmt.Println("{{ Taints and Labels }}::bgMagenta|#ffffff")
+	fmt.Println(table)
+
+	table = uitable.New()
+	table.AddRow("")
+	table.AddRow("RESOURCE", "CAPACITY", "ALLOCATABLE", "REQUESTS", "LIMITS")
+	for _, r := range doc.Resources {
+		limits := r.Limits
+		if r.Limits != "-" {
+			limits = fmt.Sprintf("%s (%s)", r.Limits, usageColor(r.LimitsPercent, 100))
+		}
+		table.AddRow(r.Name, r.Capacity, r.Allocatable,
+			fmt.Sprintf("%s (%s)", r.Requests, usageColor(r.RequestsPercent, 90)), limits)
+	}
+	_, _ = cfmt.Println("{{ Allocated Resources }}::bgMagenta|#ffffff")
+	fmt.Println(table)
+
+	var leveledList pterm.LeveledList
+	namespace := ""
+	for _, g := range doc.PodGroups {
+		if g.Namespace != namespace {
+			namespace = g.Namespace
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 0,
+				Text: cfmt.Sprintf("{{ [Namespace] }}::cyan|bold %s", namespace)})
+		}
+		level := 1
+		if g.Owner.Kind != "Pod" {
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
+				Text: cfmt.Sprintf("{{ [%s] }}::lightBlue|bold %s", g.Owner.Kind, g.Owner.Name)})
+			level = 2
+		}
+		for _, p := range g.Pods {
+			state := pterm.Green("[" + p.State + "]")
+			if p.Unhealthy {
+				state = pterm.Red("[" + p.State + "]")
+			}
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: level,
+				Text: cfmt.Sprintf("{{ [Pod] }}::blue|bold %s %s Ready: %s Restart: %d", p.Name, state, p.Ready, p.Restarts)})
+		}
+	}
+	var pods int
+	for _, g := range doc.PodGroups {
+		pods += len(g.Pods)
+	}
+	_, _ = cfmt.Printf("{{ Pods (%d) }}::bgCyan|#ffffff\n", pods)
+	if len(leveledList) > 0 {
+		root := pterm.NewTreeFromLeveledList(leveledList)
+		root.Text = ""
+		_ = pterm.DefaultTree.WithRoot(root).Render()
+	} else {
+		fmt.Println()
+	}
+
+	_, _ = cfmt.Println("{{ Drain }}::bgRed|#ffffff")
+	if len(doc.DrainBlockers) == 0 {
+		fmt.Println(pterm.Green("No PodDisruptionBudget blocks draining this node.\n"))
+		return
+	}
+	table = uitable.New()
+	table.Wrap = true
+	table.AddRow("")
+	table.AddRow("PDB", "ALLOWED", "PODS ON NODE")
+	for _, b := range doc.DrainBlockers {
+		table.AddRow(pterm.Red(b.PodDisruptionBudget.Namespace+"/"+b.PodDisruptionBudget.Name),
+			b.DisruptionsAllowed, strings.Join(b.Pods, ", "))
+	}
+	fmt.Println(table)
+}
+
+func usageColor(value, warn int64) string {
+	s := fmt.Sprintf("%d%%", value)
+	switch {
+	case value >= 100:
+		return pterm.Red(s)
+	case value >= warn:
+		return pterm.Yellow(s)
+	}
+	return pterm.Green(s)
+}
+
+func (sf *SnifferPlugin) runNodeLens(ctx context.Context, timeout time.Duration, name string, opts Options) error {
+	if opts.Report != "" || opts.Strict || opts.CheckAccess || opts.Watch || opts.TUI {
+		return errors.New("--report, --strict, --check-access, --watch and --tui are not supported for node/<name>")
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	doc, err := sf.findNodeLens(ctx, name)
+	if err != nil {
+		return contextError(ctx, timeout, err)
+	}
+	if opts.Output != "" {
+		sf.cleanMeta(&doc.Node.ObjectMeta)
+		return writeNodeOutput(os.Stdout, opts.Output, doc)
+	}
+	printNodeLens(doc)
+	sf.printNotVisible()
+	return nil
+}
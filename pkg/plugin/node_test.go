@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFindNodeLens(t *testing.T) {
+	node := testNode("node-1", "4", nil)
+	node.Status.Capacity = node.Status.Allocatable.DeepCopy()
+	withResources := func(pod *v1.Pod, cpu, memoryLimit string) *v1.Pod {
+		pod.Spec.Containers[0].Resources = v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+			Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse(memoryLimit)},
+		}
+		return pod
+	}
+	rs := metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-7d4b9"}
+	web1 := withResources(workloadPod("web-1", rs, map[string]string{"app": "web"}), "500m", "1Gi")
+	web2 := withResources(workloadPod("web-2", rs, map[string]string{"app": "web"}), "1500m", "1Gi")
+	agent := withResources(workloadPod("agent-x", metav1.OwnerReference{Kind: "DaemonSet", Name: "agent"},
+		map[string]string{"app": "agent"}), "100m", "128Mi")
+	done := workloadPod("migrate-1", metav1.OwnerReference{Kind: "Job", Name: "migrate"}, nil)
+	done.Status.Phase = v1.PodSucceeded
+	elsewhere := workloadPod("web-3", rs, map[string]string{"app": "web"})
+	elsewhere.Spec.NodeName = "node-2"
+	pdb := func(name, app string, allowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: objectMeta(name, nil),
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+		}
+	}
+	objects := []runtime.Object{
+		node, web1, web2, agent, done, elsewhere,
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-7d4b9", Namespace: testNamespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web"}}}},
+		pdb("web", "web", 1),
+		pdb("agent", "agent", 0),
+	}
+
+	sf, _ := newTestPlugin(objects...)
+	doc, err := sf.findNodeLens(context.Background(), "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantResources := []NodeResource{
+		{Name: "cpu", Capacity: "4", Allocatable: "4", Requests: "2100m", Limits: "0", RequestsPercent: 52},
+		{Name: "memory", Capacity: "4Gi", Allocatable: "4Gi", Requests: "0", Limits: "2176Mi", LimitsPercent: 53},
+		{Name: "pods", Capacity: "110", Allocatable: "110", Requests: "3", Limits: "-", RequestsPercent: 2},
+	}
+	if !reflect.DeepEqual(doc.Resources, wantResources) {
+		t.Errorf("Resources =\n%+v\nwant\n%+v", doc.Resources, wantResources)
+	}
+
+	var groups []string
+	for _, g := range doc.PodGroups {
+		for _, p := range g.Pods {
+			groups = append(groups, g.Owner.Kind+"/"+g.Owner.Name+":"+p.Name)
+		}
+	}
+	wantGroups := []string{"DaemonSet/agent:agent-x", "Deployment/web:web-1", "Deployment/web:web-2"}
+	if !reflect.DeepEqual(groups, wantGroups) {
+		t.Errorf("PodGroups = %v, want %v", groups, wantGroups)
+	}
+
+	wantBlockers := []DrainBlocker{{
+		PodDisruptionBudget: ObjectRef{Kind: "PodDisruptionBudget", Namespace: testNamespace, Name: "web"},
+		DisruptionsAllowed:  1,
+		Pods:                []string{"web-1", "web-2"},
+	}}
+	if !reflect.DeepEqual(doc.DrainBlockers, wantBlockers) {
+		t.Errorf("DrainBlockers = %+v, want %+v", doc.DrainBlockers, wantBlockers)
+	}
+}
+
+func TestFindNodeLensErrors(t *testing.T) {
+	sf, _ := newTestPlugin()
+	if _, err := sf.findNodeLens(context.Background(), "gone"); err == nil {
+		t.Error("missing node should fail")
+	}
+
+	sf, clientset := newTestPlugin(testNode("node-1", "4", nil))
+	forbid(clientset, "list", "pods")
+	forbid(clientset, "list", "poddisruptionbudgets")
+	doc, err := sf.findNodeLens(context.Background(), "node-1")
+	if err != nil {
+		t.Fatalf("forbidden lists should not fail the node lens: %v", err)
+	}
+	want := []NotVisible{{Resource: "pods", Reason: "forbidden"}, {Resource: "poddisruptionbudgets", Reason: "forbidden"}}
+	if !reflect.DeepEqual(doc.NotVisible, want) {
+		t.Errorf("NotVisible = %v, want %v", doc.NotVisible, want)
+	}
+}
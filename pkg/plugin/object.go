@@ -0,0 +1,425 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	autov1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// lastAppliedAnnotation holds the whole applied manifest, Secret data included.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// dynamicKinds are the kinds read through the dynamic client, with the
+// versions to try in order.
+func dynamicKinds() map[string][]schema.GroupVersionResource {
+	kinds := map[string][]schema.GroupVersionResource{
+		"Gateway": {
+			{Group: gatewayGroup, Version: "v1", Resource: "gateways"},
+			{Group: gatewayGroup, Version: "v1beta1", Resource: "gateways"},
+		},
+	}
+	for _, rr := range routeResources {
+		for _, version := range rr.Versions {
+			kinds[rr.Kind] = append(kinds[rr.Kind], schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: rr.Resource})
+		}
+	}
+	return kinds
+}
+
+// getObject reads any object that can show up in the relationship tree.
+func (sf *SnifferPlugin) getObject(ctx context.Context, ref ObjectRef) (runtime.Object, error) {
+	c, ns, name, opts := sf.Clientset, ref.Namespace, ref.Name, metav1.GetOptions{}
+	switch ref.Kind {
+	case "Pod":
+		return c.CoreV1().Pods(ns).Get(ctx, name, opts)
+	case "Node":
+		return c.CoreV1().Nodes().Get(ctx, name, opts)
+	case "Namespace":
+		return c.CoreV1().Namespaces().Get(ctx, name, opts)
+	case "Service":
+		return c.CoreV1().Services(ns).Get(ctx, name, opts)
+	case "ServiceAccount":
+		return c.CoreV1().ServiceAccounts(ns).Get(ctx, name, opts)
+	case "ConfigMap":
+		return c.CoreV1().ConfigMaps(ns).Get(ctx, name, opts)
+	case "Secret":
+		return c.CoreV1().Secrets(ns).Get(ctx, name, opts)
+	case "PersistentVolumeClaim":
+		return c.CoreV1().PersistentVolumeClaims(ns).Get(ctx, name, opts)
+	case "PersistentVolume":
+		return c.CoreV1().PersistentVolumes().Get(ctx, name, opts)
+	case "Deployment":
+		return c.AppsV1().Deployments(ns).Get(ctx, name, opts)
+	case "StatefulSet":
+		return c.AppsV1().StatefulSets(ns).Get(ctx, name, opts)
+	case "DaemonSet":
+		return c.AppsV1().DaemonSets(ns).Get(ctx, name, opts)
+	case "ReplicaSet":
+		return c.AppsV1().ReplicaSets(ns).Get(ctx, name, opts)
+	case "Job":
+		return c.BatchV1().Jobs(ns).Get(ctx, name, opts)
+	case "CronJob":
+		return c.BatchV1().CronJobs(ns).Get(ctx, name, opts)
+	case "Ingress":
+		return c.NetworkingV1().Ingresses(ns).Get(ctx, name, opts)
+	case "HorizontalPodAutoscaler":
+		return c.AutoscalingV1().HorizontalPodAutoscalers(ns).Get(ctx, name, opts)
+	case "PodDisruptionBudget":
+		return c.PolicyV1().PodDisruptionBudgets(ns).Get(ctx, name, opts)
+	}
+	gvrs, ok := dynamicKinds()[ref.Kind]
+	if !ok || sf.DynamicClient == nil {
+		return nil, errors.Errorf("%s objects cannot be read", ref.Kind)
+	}
+	var err error
+	for _, gvr := range gvrs {
+		var u *unstructured.Unstructured
+		u, err = sf.DynamicClient.Resource(gvr).Namespace(ns).Get(ctx, name, opts)
+		if err == nil {
+			return u, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// objectYAML renders an object the way -o yaml would, with managed fields and
+// the last-applied configuration dropped, the redact rules applied to pod
+// templates and Secret values hidden.
+func (sf *SnifferPlugin) objectYAML(obj runtime.Object) (string, error) {
+	obj = obj.DeepCopyObject()
+	if gvks, _, err := scheme.Scheme.ObjectKinds(obj); err == nil && len(gvks) > 0 {
+		obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	}
+	if m, err := meta.Accessor(obj); err == nil {
+		m.SetManagedFields(nil)
+		annotations := m.GetAnnotations()
+		delete(annotations, lastAppliedAnnotation)
+		m.SetAnnotations(sf.redactMap(annotations))
+	}
+	switch o := obj.(type) {
+	case *v1.Pod:
+		sf.redactPod(o)
+	case *appsv1.Deployment:
+		sf.redactPodSpec(&o.Spec.Template.Spec)
+	case *appsv1.StatefulSet:
+		sf.redactPodSpec(&o.Spec.Template.Spec)
+	case *appsv1.DaemonSet:
+		sf.redactPodSpec(&o.Spec.Template.Spec)
+	case *appsv1.ReplicaSet:
+		sf.redactPodSpec(&o.Spec.Template.Spec)
+	case *batchv1.Job:
+		sf.redactPodSpec(&o.Spec.Template.Spec)
+	case *batchv1.CronJob:
+		sf.redactPodSpec(&o.Spec.JobTemplate.Spec.Template.Spec)
+	case *v1.ConfigMap:
+		o.Data = sf.redactMap(o.Data)
+	case *v1.Secret:
+		values := make(map[string]string, len(o.Data)+len(o.StringData))
+		for k := range o.Data {
+			values[k] = redactedValue
+		}
+		for k := range o.StringData {
+			values[k] = redactedValue
+		}
+		o.Data, o.StringData = nil, values
+	}
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to render YAML")
+	}
+	return string(out), nil
+}
+
+// objectDetails summarizes an object in a few `Field: value` lines.
+func objectDetails(obj runtime.Object, now time.Time) []string {
+	var lines []string
+	add := func(field string, value interface{}) {
+		lines = append(lines, fmt.Sprintf("%-16s %v", field+":", value))
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	add("Name", m.GetName())
+	if m.GetNamespace() != "" {
+		add("Namespace", m.GetNamespace())
+	}
+	add("Age", translateAge(m.GetCreationTimestamp(), now))
+	if len(m.GetLabels()) > 0 {
+		add("Labels", labelList(m.GetLabels()))
+	}
+	if own := metav1.GetControllerOfNoCopy(m); own != nil {
+		add("Controlled By", own.Kind+"/"+own.Name)
+	}
+
+	switch o := obj.(type) {
+	case *v1.Pod:
+		s := summarizePod(o)
+		add("Status", s.State)
+		add("Ready", s.Ready)
+		add("Restarts", s.Restarts)
+		add("Node", s.Node)
+		add("IP", s.PodIP)
+		add("QoS Class", o.Status.QOSClass)
+		add("Service Account", o.Spec.ServiceAccountName)
+		states := make(map[string]v1.ContainerStatus)
+		for _, c := range o.Status.ContainerStatuses {
+			states[c.Name] = c
+		}
+		lines = append(lines, "Containers:")
+		for _, c := range o.Spec.Containers {
+			state := "Waiting"
+			if status, ok := states[c.Name]; ok {
+				state = fmt.Sprintf("%s, %d restarts", containerState(status.State), status.RestartCount)
+			}
+			lines = append(lines, fmt.Sprintf("  %s  %s  (%s)", c.Name, c.Image, state))
+		}
+	case *appsv1.Deployment:
+		add("Replicas", fmt.Sprintf("%d desired, %d updated, %d ready, %d available",
+			replicaCount(o.Spec.Replicas), o.Status.UpdatedReplicas, o.Status.ReadyReplicas, o.Status.AvailableReplicas))
+		add("Strategy", o.Spec.Strategy.Type)
+		add("Selector", metav1.FormatLabelSelector(o.Spec.Selector))
+		add("Images", templateImages(o.Spec.Template.Spec))
+	case *appsv1.StatefulSet:
+		add("Replicas", fmt.Sprintf("%d desired, %d current, %d updated, %d ready",
+			replicaCount(o.Spec.Replicas), o.Status.CurrentReplicas, o.Status.UpdatedReplicas, o.Status.ReadyReplicas))
+		add("Service Name", o.Spec.ServiceName)
+		add("Selector", metav1.FormatLabelSelector(o.Spec.Selector))
+		add("Images", templateImages(o.Spec.Template.Spec))
+	case *appsv1.DaemonSet:
+		add("Pods", fmt.Sprintf("%d desired, %d current, %d updated, %d ready, %d available",
+			o.Status.DesiredNumberScheduled, o.Status.CurrentNumberScheduled, o.Status.UpdatedNumberScheduled,
+			o.Status.NumberReady, o.Status.NumberAvailable))
+		add("Selector", metav1.FormatLabelSelector(o.Spec.Selector))
+		add("Images", templateImages(o.Spec.Template.Spec))
+	case *appsv1.ReplicaSet:
+		add("Replicas", fmt.Sprintf("%d desired, %d ready, %d available",
+			replicaCount(o.Spec.Replicas), o.Status.ReadyReplicas, o.Status.AvailableReplicas))
+		add("Images", templateImages(o.Spec.Template.Spec))
+	case *batchv1.Job:
+		add("Completions", fmt.Sprintf("%d/%d", o.Status.Succeeded, replicaCount(o.Spec.Completions)))
+		add("Pods", fmt.Sprintf("%d active, %d succeeded, %d failed", o.Status.Active, o.Status.Succeeded, o.Status.Failed))
+		add("Images", templateImages(o.Spec.Template.Spec))
+	case *batchv1.CronJob:
+		add("Schedule", o.Spec.Schedule)
+		add("Suspend", o.Spec.Suspend != nil && *o.Spec.Suspend)
+		if o.Status.LastScheduleTime != nil {
+			add("Last Schedule", translateAge(*o.Status.LastScheduleTime, now)+" ago")
+		}
+		add("Active Jobs", len(o.Status.Active))
+	case *v1.Service:
+		add("Type", o.Spec.Type)
+		add("Cluster IP", o.Spec.ClusterIP)
+		for _, ing := range o.Status.LoadBalancer.Ingress {
+			add("Load Balancer", ing.IP+ing.Hostname)
+		}
+		for _, p := range o.Spec.Ports {
+			port := fmt.Sprintf("%d/%s -> %s", p.Port, p.Protocol, p.TargetPort.String())
+			if p.NodePort != 0 {
+				port += fmt.Sprintf(" (node port %d)", p.NodePort)
+			}
+			if p.Name != "" {
+				port = p.Name + " " + port
+			}
+			add("Port", port)
+		}
+		add("Selector", labelList(o.Spec.Selector))
+	case *netv1.Ingress:
+		if o.Spec.IngressClassName != nil {
+			add("Class", *o.Spec.IngressClassName)
+		}
+		for _, rule := range o.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service != nil {
+					add("Rule", fmt.Sprintf("%s%s -> %s", rule.Host, path.Path, path.Backend.Service.Name))
+				}
+			}
+		}
+		for _, tls := range o.Spec.TLS {
+			add("TLS", fmt.Sprintf("%s (%s)", strings.Join(tls.Hosts, ","), tls.SecretName))
+		}
+	case *v1.PersistentVolumeClaim:
+		add("Status", o.Status.Phase)
+		add("Volume", o.Spec.VolumeName)
+		if size, ok := o.Status.Capacity[v1.ResourceStorage]; ok {
+			add("Capacity", size.String())
+		}
+		add("Access Modes", accessModes(o.Spec.AccessModes))
+		if o.Spec.StorageClassName != nil {
+			add("Storage Class", *o.Spec.StorageClassName)
+		}
+	case *v1.PersistentVolume:
+		add("Status", o.Status.Phase)
+		if size, ok := o.Spec.Capacity[v1.ResourceStorage]; ok {
+			add("Capacity", size.String())
+		}
+		add("Access Modes", accessModes(o.Spec.AccessModes))
+		add("Reclaim Policy", o.Spec.PersistentVolumeReclaimPolicy)
+		add("Storage Class", o.Spec.StorageClassName)
+		if o.Spec.ClaimRef != nil {
+			add("Claim", o.Spec.ClaimRef.Namespace+"/"+o.Spec.ClaimRef.Name)
+		}
+	case *v1.ConfigMap:
+		sizes := make(map[string]int, len(o.Data)+len(o.BinaryData))
+		for k, v := range o.Data {
+			sizes[k] = len(v)
+		}
+		for k, v := range o.BinaryData {
+			sizes[k] = len(v)
+		}
+		lines = append(lines, keySizes(sizes)...)
+	case *v1.Secret:
+		add("Type", o.Type)
+		sizes := make(map[string]int, len(o.Data))
+		for k, v := range o.Data {
+			sizes[k] = len(v)
+		}
+		lines = append(lines, keySizes(sizes)...)
+	case *v1.ServiceAccount:
+		for _, s := range o.Secrets {
+			add("Secret", s.Name)
+		}
+		for _, s := range o.ImagePullSecrets {
+			add("Pull Secret", s.Name)
+		}
+		if o.AutomountServiceAccountToken != nil {
+			add("Automount Token", *o.AutomountServiceAccountToken)
+		}
+	case *v1.Node:
+		ready := "NotReady"
+		if nodeReady(o) {
+			ready = "Ready"
+		}
+		if o.Spec.Unschedulable {
+			ready += ",SchedulingDisabled"
+		}
+		add("Status", ready)
+		for _, c := range o.Status.Conditions {
+			if c.Type != v1.NodeReady && c.Status == v1.ConditionTrue {
+				add("Condition", fmt.Sprintf("%s: %s", c.Type, c.Message))
+			}
+		}
+		for _, a := range o.Status.Addresses {
+			add(string(a.Type), a.Address)
+		}
+		add("Kubelet", o.Status.NodeInfo.KubeletVersion)
+		add("OS Image", o.Status.NodeInfo.OSImage)
+		add("Runtime", o.Status.NodeInfo.ContainerRuntimeVersion)
+		for _, name := range nodeResourceOrder {
+			if alloc, ok := o.Status.Allocatable[name]; ok {
+				add(string(name), fmt.Sprintf("%s allocatable", alloc.String()))
+			}
+		}
+		for _, taint := range o.Spec.Taints {
+			add("Taint", taint.ToString())
+		}
+	case *v1.Namespace:
+		add("Status", o.Status.Phase)
+	case *autov1.HorizontalPodAutoscaler:
+		add("Scale Target", o.Spec.ScaleTargetRef.Kind+"/"+o.Spec.ScaleTargetRef.Name)
+		add("Replicas", fmt.Sprintf("%d current, %d desired, %d-%d",
+			o.Status.CurrentReplicas, o.Status.DesiredReplicas, replicaCount(o.Spec.MinReplicas), o.Spec.MaxReplicas))
+		if o.Spec.TargetCPUUtilizationPercentage != nil {
+			current := "<unknown>"
+			if o.Status.CurrentCPUUtilizationPercentage != nil {
+				current = fmt.Sprintf("%d%%", *o.Status.CurrentCPUUtilizationPercentage)
+			}
+			add("CPU", fmt.Sprintf("%s / %d%%", current, *o.Spec.TargetCPUUtilizationPercentage))
+		}
+	case *policyv1.PodDisruptionBudget:
+		if o.Spec.MinAvailable != nil {
+			add("Min Available", o.Spec.MinAvailable.String())
+		}
+		if o.Spec.MaxUnavailable != nil {
+			add("Max Unavailable", o.Spec.MaxUnavailable.String())
+		}
+		add("Healthy", fmt.Sprintf("%d current, %d desired", o.Status.CurrentHealthy, o.Status.DesiredHealthy))
+		add("Disruptions", o.Status.DisruptionsAllowed)
+		add("Selector", metav1.FormatLabelSelector(o.Spec.Selector))
+	case *unstructured.Unstructured:
+		if o.GetKind() != "Gateway" {
+			route := parseRoute(o.GetKind(), *o)
+			if len(route.Hostnames) > 0 {
+				add("Hostnames", strings.Join(route.Hostnames, ", "))
+			}
+			for _, p := range route.Parents {
+				add("Parent", p.Kind+"/"+p.Name)
+			}
+			add("Backends", strings.Join(route.Backends, ", "))
+		}
+	}
+	return lines
+}
+
+func replicaCount(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func labelList(m map[string]string) string {
+	if len(m) == 0 {
+		return "<none>"
+	}
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func templateImages(spec v1.PodSpec) string {
+	var images []string
+	for _, c := range spec.Containers {
+		images = append(images, c.Image)
+	}
+	return strings.Join(images, ", ")
+}
+
+func accessModes(modes []v1.PersistentVolumeAccessMode) string {
+	var out []string
+	for _, m := range modes {
+		out = append(out, string(m))
+	}
+	return strings.Join(out, ",")
+}
+
+// keySizes lists data keys with their size, never their values.
+func keySizes(sizes map[string]int) []string {
+	keys := make([]string, 0, len(sizes))
+	for k := range sizes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := []string{fmt.Sprintf("%-16s %d keys", "Data:", len(keys))}
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("  %s  %d bytes", k, sizes[k]))
+	}
+	return lines
+}
@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// NewOfflineSnifferPlugin loads manifests from files and directories into an
+// in-memory store, so the same lookups as against a live cluster can run on a
+// `kubectl get -o yaml` output or a `kubectl cluster-info dump` directory.
+// A file named "-" is read from standard input.
+func NewOfflineSnifferPlugin(files, dirs []string) (*SnifferPlugin, error) {
+	objects, err := loadManifests(files, dirs)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("no Kubernetes objects found in the given files")
+	}
+
+	var typed, untyped []runtime.Object
+	for _, u := range objects {
+		gvk := u.GroupVersionKind()
+		if !scheme.Scheme.Recognizes(gvk) {
+			untyped = append(untyped, u)
+			continue
+		}
+		obj, err := scheme.Scheme.New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s %s/%s", gvk.Kind, u.GetNamespace(), u.GetName())
+		}
+		typed = append(typed, obj)
+	}
+
+	return NewSnifferPluginForClients(
+		fake.NewSimpleClientset(typed...),
+		dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), routeListKinds(), untyped...),
+	), nil
+}
+
+// routeListKinds registers the route list kinds with the in-memory dynamic client.
+func routeListKinds() map[schema.GroupVersionResource]string {
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for _, rr := range routeResources {
+		for _, version := range rr.Versions {
+			listKinds[schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: rr.Resource}] = rr.Kind + "List"
+		}
+	}
+	return listKinds
+}
+
+// loadManifests reads every object from the files and from all YAML and JSON
+// files below the directories, flattening lists. When the same object appears
+// more than once the last occurrence wins.
+func loadManifests(files, dirs []string) ([]*unstructured.Unstructured, error) {
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && manifestExtensions[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read directory %s", dir)
+		}
+	}
+
+	var objects []*unstructured.Unstructured
+	index := make(map[string]int)
+	for _, file := range files {
+		loaded, err := loadManifestFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range loaded {
+			key := u.GroupVersionKind().String() + "/" + u.GetNamespace() + "/" + u.GetName()
+			if i, ok := index[key]; ok {
+				objects[i] = u
+				continue
+			}
+			index[key] = len(objects)
+			objects = append(objects, u)
+		}
+	}
+	return objects, nil
+}
+
+func loadManifestFile(file string) ([]*unstructured.Unstructured, error) {
+	var r io.Reader
+	if file == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open %s", file)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var objects []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		if !u.IsList() {
+			if u.GetKind() != "" && u.GetName() != "" {
+				objects = append(objects, u)
+			}
+			continue
+		}
+		list, err := u.ToList()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse list in %s", file)
+		}
+		// Typed lists such as the PodList files of a cluster-info dump leave
+		// the kind of their items empty.
+		itemKind := strings.TrimSuffix(u.GetKind(), "List")
+		for i := range list.Items {
+			item := &list.Items[i]
+			if item.GetKind() == "" && itemKind != "" {
+				item.SetAPIVersion(u.GetAPIVersion())
+				item.SetKind(itemKind)
+			}
+			if item.GetKind() != "" && item.GetName() != "" {
+				objects = append(objects, item)
+			}
+		}
+	}
+	return objects, nil
+}
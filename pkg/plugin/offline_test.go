@@ -0,0 +1,212 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const offlinePods = `{"kind":"PodList","apiVersion":"v1","items":[
+{"metadata":{"name":"web-7d4b9-abcde","namespace":"default","labels":{"app":"web"},
+ "ownerReferences":[{"apiVersion":"apps/v1","kind":"ReplicaSet","name":"web-7d4b9","uid":"1"}]},
+ "spec":{"nodeName":"node-1","containers":[{"name":"app","image":"nginx"}]},"status":{"phase":"Running"}}]}`
+
+const offlineManifests = `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: ReplicaSet
+  metadata:
+    name: web-7d4b9
+    namespace: default
+    ownerReferences:
+    - {apiVersion: apps/v1, kind: Deployment, name: web, uid: "2"}
+  status: {replicas: 1, readyReplicas: 1}
+- apiVersion: v1
+  kind: Service
+  metadata: {name: web, namespace: default}
+  spec:
+    selector: {app: web}
+---
+apiVersion: v1
+kind: Node
+metadata: {name: node-1}
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata: {name: web, namespace: default}
+spec:
+  rules:
+  - backendRefs: [{name: web}]
+---
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: default}
+spec:
+  selector: {app: web}
+  clusterIP: 10.0.0.1
+`
+
+func writeOfflineDump(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "default"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"default/pods.json": offlinePods,
+		"manifests.yaml":    offlineManifests,
+		"default/logs.txt":  "not a manifest",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadManifests(t *testing.T) {
+	dir := writeOfflineDump(t)
+	tests := []struct {
+		name  string
+		files []string
+		dirs  []string
+		want  []string
+	}{
+		{
+			name:  "typed list",
+			files: []string{filepath.Join(dir, "default/pods.json")},
+			want:  []string{"Pod/default/web-7d4b9-abcde"},
+		},
+		{
+			name:  "multi-document yaml with list",
+			files: []string{filepath.Join(dir, "manifests.yaml")},
+			want:  []string{"HTTPRoute/default/web", "Node//node-1", "ReplicaSet/default/web-7d4b9", "Service/default/web"},
+		},
+		{
+			name: "dump directory",
+			dirs: []string{dir},
+			want: []string{"HTTPRoute/default/web", "Node//node-1", "Pod/default/web-7d4b9-abcde",
+				"ReplicaSet/default/web-7d4b9", "Service/default/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := loadManifests(tt.files, tt.dirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, u := range objects {
+				got = append(got, u.GetKind()+"/"+u.GetNamespace()+"/"+u.GetName())
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadManifests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadManifestsLastWins(t *testing.T) {
+	objects, err := loadManifests([]string{filepath.Join(writeOfflineDump(t), "manifests.yaml")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range objects {
+		if u.GetKind() != "Service" {
+			continue
+		}
+		ip, _, _ := unstructured.NestedString(u.Object, "spec", "clusterIP")
+		if ip != "10.0.0.1" {
+			t.Errorf("duplicate Service should keep the last document, got clusterIP %q", ip)
+		}
+	}
+}
+
+func TestOfflineSnifferPlugin(t *testing.T) {
+	sf, err := NewOfflineSnifferPlugin(nil, []string{writeOfflineDump(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = sf.findPodByName(ctx, "web", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if sf.AllInfo.Workload.Name != "web" || sf.AllInfo.Node == nil {
+		t.Errorf("workload = %+v, node found = %v", sf.AllInfo.Workload, sf.AllInfo.Node != nil)
+	}
+	if len(sf.AllInfo.SvcList.Items) != 1 || len(sf.AllInfo.Routes) != 1 {
+		t.Errorf("services = %d, routes = %d, want 1 each", len(sf.AllInfo.SvcList.Items), len(sf.AllInfo.Routes))
+	}
+}
+
+// findOfflineWith looks up the offline dump's pod together with an extra manifest.
+func findOfflineWith(t *testing.T, manifest string) *SnifferPlugin {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "extra.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sf, err := NewOfflineSnifferPlugin([]string{path}, []string{writeOfflineDump(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = sf.findPodByName(ctx, "web", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	return sf
+}
+
+func TestOfflineBarePVC(t *testing.T) {
+	sf := findOfflineWith(t, `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata: {name: web-data, namespace: default, labels: {app: web}}
+`)
+	if sf.AllInfo.PvcList == nil || len(sf.AllInfo.PvcList.Items) != 1 {
+		t.Fatalf("pvcs = %v, want the bare PVC", sf.AllInfo.PvcList)
+	}
+	if err := sf.printResource(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOfflineBareHPA(t *testing.T) {
+	sf := findOfflineWith(t, `apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata: {name: web, namespace: default}
+spec:
+  scaleTargetRef: {apiVersion: apps/v1, kind: Deployment, name: web}
+  maxReplicas: 3
+`)
+	if sf.AllInfo.Hpa == nil || sf.AllInfo.Hpa.Spec.MinReplicas != nil {
+		t.Fatalf("hpa = %v, want the HPA without minReplicas", sf.AllInfo.Hpa)
+	}
+	if err := sf.printResource(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOfflineSnifferPluginEmpty(t *testing.T) {
+	if _, err := NewOfflineSnifferPlugin(nil, []string{t.TempDir()}); err == nil {
+		t.Error("expected an error for a directory without manifests")
+	}
+}
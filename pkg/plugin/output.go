@@ -0,0 +1,230 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	autov1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	documentAPIVersion = "pod-lens.guoxudong.io/v1alpha1"
+	documentKind       = "PodLens"
+)
+
+var supportedOutputs = []string{"json", "yaml", "dot", "mermaid"}
+
+// Document is the machine-readable form of a lens result. Fields are only
+// ever added to a given apiVersion, never renamed or removed.
+type Document struct {
+	APIVersion              string                          `json:"apiVersion"`
+	Kind                    string                          `json:"kind"`
+	Pod                     *v1.Pod                         `json:"pod,omitempty"`
+	PodTemplate             *v1.PodTemplateSpec             `json:"podTemplate,omitempty"`
+	Node                    *v1.Node                        `json:"node,omitempty"`
+	Workload                Workload                        `json:"workload"`
+	Pods                    []PodSummary                    `json:"pods,omitempty"`
+	LabelSelector           string                          `json:"labelSelector,omitempty"`
+	Deployments             []appsv1.Deployment             `json:"deployments"`
+	StatefulSets            []appsv1.StatefulSet            `json:"statefulSets"`
+	DaemonSets              []appsv1.DaemonSet              `json:"daemonSets"`
+	Services                []v1.Service                    `json:"services"`
+	Ingresses               []netv1.Ingress                 `json:"ingresses"`
+	Routes                  []Route                         `json:"routes"`
+	PersistentVolumeClaims  []v1.PersistentVolumeClaim      `json:"persistentVolumeClaims"`
+	ConfigMaps              []v1.ConfigMap                  `json:"configMaps"`
+	Secrets                 []SecretSummary                 `json:"secrets"`
+	References              []Reference                     `json:"references"`
+	HorizontalPodAutoscaler *autov1.HorizontalPodAutoscaler `json:"horizontalPodAutoscaler,omitempty"`
+	PodDisruptionBudgets    []policyv1.PodDisruptionBudget  `json:"podDisruptionBudgets"`
+	Relationships           []Relationship                  `json:"relationships"`
+	Events                  []TimelineEvent                 `json:"events"`
+	Diagnostics             []ContainerDiagnosis            `json:"diagnostics"`
+	Logs                    []ContainerLogs                 `json:"logs,omitempty"`
+	Scheduling              *SchedulingAnalysis             `json:"scheduling,omitempty"`
+	NotVisible              []NotVisible                    `json:"notVisible,omitempty"`
+}
+
+// SecretSummary describes a secret without exposing its data.
+type SecretSummary struct {
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Type      v1.SecretType `json:"type"`
+	Keys      []string      `json:"keys"`
+}
+
+func validateOutput(output string) error {
+	if output == "" {
+		return nil
+	}
+	for _, o := range supportedOutputs {
+		if o == output {
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported output format %q, expected one of %v", output, supportedOutputs)
+}
+
+func (sf *SnifferPlugin) buildDocument() *Document {
+	doc := &Document{
+		APIVersion:             documentAPIVersion,
+		Kind:                   documentKind,
+		Pod:                    sf.PodObject.DeepCopy(),
+		Node:                   sf.AllInfo.Node.DeepCopy(),
+		Workload:               sf.AllInfo.Workload,
+		LabelSelector:          sf.LabelSelector,
+		Deployments:            []appsv1.Deployment{},
+		StatefulSets:           []appsv1.StatefulSet{},
+		DaemonSets:             []appsv1.DaemonSet{},
+		Services:               []v1.Service{},
+		Ingresses:              []netv1.Ingress{},
+		Routes:                 append([]Route{}, sf.AllInfo.Routes...),
+		PersistentVolumeClaims: []v1.PersistentVolumeClaim{},
+		ConfigMaps:             []v1.ConfigMap{},
+		Secrets:                []SecretSummary{},
+		References:             append([]Reference{}, sf.AllInfo.References...),
+		PodDisruptionBudgets:   []policyv1.PodDisruptionBudget{},
+		Relationships:          sf.buildRelationships(),
+		Events:                 append([]TimelineEvent{}, sf.AllInfo.Events...),
+		Diagnostics:            append([]ContainerDiagnosis{}, sf.AllInfo.Diagnostics...),
+		Logs:                   sf.AllInfo.Logs,
+		Scheduling:             sf.AllInfo.Scheduling,
+		NotVisible:             sf.AllInfo.NotVisible,
+	}
+	sf.cleanMeta(&doc.Pod.ObjectMeta)
+	sf.redactPod(doc.Pod)
+	if sf.fromWorkload {
+		// The pod was built from the workload's template and has no status or
+		// UID, so it must not pass for a real pod.
+		doc.Pods = sf.podSummaries()
+		doc.PodTemplate = &v1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: doc.Pod.Labels, Annotations: doc.Pod.Annotations},
+			Spec:       doc.Pod.Spec,
+		}
+		doc.Pod = nil
+	}
+	if doc.Node != nil {
+		sf.cleanMeta(&doc.Node.ObjectMeta)
+	}
+	if l := sf.AllInfo.DeployList; l != nil {
+		for _, item := range l.Items {
+			i := *item.DeepCopy()
+			sf.cleanMeta(&i.ObjectMeta)
+			sf.redactPodSpec(&i.Spec.Template.Spec)
+			doc.Deployments = append(doc.Deployments, i)
+		}
+	}
+	if l := sf.AllInfo.StsList; l != nil {
+		for _, item := range l.Items {
+			i := *item.DeepCopy()
+			sf.cleanMeta(&i.ObjectMeta)
+			sf.redactPodSpec(&i.Spec.Template.Spec)
+			doc.StatefulSets = append(doc.StatefulSets, i)
+		}
+	}
+	if l := sf.AllInfo.DsList; l != nil {
+		for _, item := range l.Items {
+			i := *item.DeepCopy()
+			sf.cleanMeta(&i.ObjectMeta)
+			sf.redactPodSpec(&i.Spec.Template.Spec)
+			doc.DaemonSets = append(doc.DaemonSets, i)
+		}
+	}
+	if l := sf.AllInfo.SvcList; l != nil {
+		for _, i := range l.Items {
+			sf.cleanMeta(&i.ObjectMeta)
+			doc.Services = append(doc.Services, i)
+		}
+	}
+	if l := sf.AllInfo.IngList; l != nil {
+		for _, i := range l.Items {
+			sf.cleanMeta(&i.ObjectMeta)
+			doc.Ingresses = append(doc.Ingresses, i)
+		}
+	}
+	if l := sf.AllInfo.PvcList; l != nil {
+		for _, i := range l.Items {
+			sf.cleanMeta(&i.ObjectMeta)
+			doc.PersistentVolumeClaims = append(doc.PersistentVolumeClaims, i)
+		}
+	}
+	if l := sf.AllInfo.ConfigMapList; l != nil {
+		for _, i := range l.Items {
+			sf.cleanMeta(&i.ObjectMeta)
+			i.Data = sf.redactMap(i.Data)
+			doc.ConfigMaps = append(doc.ConfigMaps, i)
+		}
+	}
+	if l := sf.AllInfo.SecretList; l != nil {
+		for _, i := range l.Items {
+			doc.Secrets = append(doc.Secrets, summarizeSecret(i))
+		}
+	}
+	if sf.AllInfo.Hpa != nil {
+		doc.HorizontalPodAutoscaler = sf.AllInfo.Hpa.DeepCopy()
+		sf.cleanMeta(&doc.HorizontalPodAutoscaler.ObjectMeta)
+	}
+	for _, pdb := range sf.AllInfo.Pdbs {
+		i := *pdb.DeepCopy()
+		sf.cleanMeta(&i.ObjectMeta)
+		doc.PodDisruptionBudgets = append(doc.PodDisruptionBudgets, i)
+	}
+	return doc
+}
+
+func (sf *SnifferPlugin) printOutput(w io.Writer, output string) error {
+	switch output {
+	case "dot":
+		return newGraph(sf.buildRelationships()).WriteDot(w)
+	case "mermaid":
+		return newGraph(sf.buildRelationships()).WriteMermaid(w)
+	}
+	return writeDocument(w, output, sf.buildDocument())
+}
+
+func writeDocument(w io.Writer, output string, doc interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch output {
+	case "json":
+		data, err = json.MarshalIndent(doc, "", "  ")
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.Marshal(doc)
+	default:
+		return validateOutput(output)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to encode output")
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func summarizeSecret(sec v1.Secret) SecretSummary {
+	keys := make([]string, 0, len(sec.Data))
+	for k := range sec.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return SecretSummary{
+		Name:      sec.Name,
+		Namespace: sec.Namespace,
+		Type:      sec.Type,
+		Keys:      keys,
+	}
+}
+
+func stripManagedFields(meta *metav1.ObjectMeta) {
+	meta.ManagedFields = nil
+}
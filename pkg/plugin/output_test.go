@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testCluster() []runtime.Object {
+	pod := testPod()
+	pod.Spec.Volumes = []v1.Volume{{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+		LocalObjectReference: v1.LocalObjectReference{Name: "web-config"}}}}}
+	pod.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{{Name: "config", MountPath: "/etc/web"}}
+	return []runtime.Object{
+		pod,
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-7d4b9", Namespace: testNamespace,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web"}}},
+			Status: appsv1.ReplicaSetStatus{Replicas: 1, ReadyReplicas: 1},
+		},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", map[string]string{"app": "web"})},
+		&v1.Service{ObjectMeta: objectMeta("web", nil), Spec: v1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+		&v1.ConfigMap{ObjectMeta: objectMeta("web-config", nil)},
+		&v1.Secret{ObjectMeta: objectMeta("web-tls", map[string]string{"app": "web"}),
+			Data: map[string][]byte{"tls.key": []byte("secret")}},
+	}
+}
+
+func TestFindRelated(t *testing.T) {
+	sf, _ := newTestPlugin(testCluster()...)
+	ctx := context.Background()
+	if err := sf.findPodByName(ctx, "web", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := sf.printOutput(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var doc Document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Workload.Name != "web" || doc.Node == nil || len(doc.Services) != 1 || len(doc.Deployments) != 1 {
+		t.Errorf("unexpected document: workload=%+v node=%v services=%d deployments=%d",
+			doc.Workload, doc.Node != nil, len(doc.Services), len(doc.Deployments))
+	}
+	if strings.Contains(buf.String(), "c2VjcmV0") {
+		t.Errorf("secret data leaked into the output")
+	}
+
+	want := map[string]bool{
+		"Deployment/web manages Pod/web-7d4b9-abcde":      false,
+		"Service/web selects Pod/web-7d4b9-abcde":         false,
+		"Pod/web-7d4b9-abcde mounts ConfigMap/web-config": false,
+		"Pod/web-7d4b9-abcde scheduled-on Node/node-1":    false,
+	}
+	for _, r := range doc.Relationships {
+		key := r.From.Kind + "/" + r.From.Name + " " + r.Type + " " + r.To.Kind + "/" + r.To.Name
+		if _, ok := want[key]; ok {
+			want[key] = true
+		}
+	}
+	for rel, found := range want {
+		if !found {
+			t.Errorf("missing relationship %q in %+v", rel, doc.Relationships)
+		}
+	}
+}
+
+func TestPrintOutputGraphs(t *testing.T) {
+	sf, _ := newTestPlugin(testCluster()...)
+	ctx := context.Background()
+	if err := sf.findPodByName(ctx, "web", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{output: "dot", want: `"Pod/default/web-7d4b9-abcde" -> "Node/node-1" [label="scheduled-on"];`},
+		{output: "mermaid", want: "graph LR"},
+		{output: "yaml", want: "kind: PodLens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := sf.printOutput(&buf, tt.output); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("printOutput(%s) does not contain %q:\n%s", tt.output, tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	netv1 "k8s.io/api/networking/v1"
+	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 
 	select_pod "github.com/sunny0826/kubectl-pod-lens/pkg/select-pod"
 	"k8s.io/klog"
 
-	mapset "github.com/deckarep/golang-set"
 	"github.com/gosuri/uitable"
 
 	"github.com/i582/cfmt/cmd/cfmt"
@@ -22,39 +25,64 @@ import (
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 type Workload struct {
-	Type     string
-	Name     string
-	Replicas string
-	Status   bool
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Replicas string `json:"replicas"`
+	Status   bool   `json:"unhealthy"`
 }
 
 type AllInfo struct {
-	Node          *v1.Node
-	DeployList    *appsv1.DeploymentList
-	StsList       *appsv1.StatefulSetList
-	DsList        *appsv1.DaemonSetList
-	SvcList       *v1.ServiceList
-	IngList       *netv1.IngressList
-	PvcList       *v1.PersistentVolumeClaimList
-	ConfigMapList *v1.ConfigMapList
-	SecretList    *v1.SecretList
-	Hpa           *autov1.HorizontalPodAutoscaler
-	Pdbs          []*policyv1.PodDisruptionBudget
-	Workload      Workload
+	Node           *v1.Node
+	DeployList     *appsv1.DeploymentList
+	StsList        *appsv1.StatefulSetList
+	DsList         *appsv1.DaemonSetList
+	SvcList        *v1.ServiceList
+	IngList        *netv1.IngressList
+	Routes         []Route
+	PvcList        *v1.PersistentVolumeClaimList
+	ConfigMapList  *v1.ConfigMapList
+	SecretList     *v1.SecretList
+	Hpa            *autov1.HorizontalPodAutoscaler
+	Pdbs           []*policyv1.PodDisruptionBudget
+	Events         []TimelineEvent
+	Logs           []ContainerLogs
+	Scheduling     *SchedulingAnalysis
+	Diagnostics    []ContainerDiagnosis
+	References     []Reference
+	ServiceAccount *v1.ServiceAccount
+	Workload       Workload
+	// Pods are the pods of the workload when the lens starts from one.
+	Pods       []v1.Pod
+	NotVisible []NotVisible
 }
 
 type SnifferPlugin struct {
-	config        *rest.Config
-	Clientset     *kubernetes.Clientset
+	mu            sync.Mutex
+	Clientset     kubernetes.Interface
+	DynamicClient dynamic.Interface
 	PodObject     *v1.Pod
 	LabelSelector string
 	AllInfo       AllInfo
+
+	resources map[string]bool
+	redact    []*regexp.Regexp
+	// logGrep filters the container logs of --logs.
+	logGrep *regexp.Regexp
+	// fromWorkload is set when PodObject is built from a workload's pod template.
+	fromWorkload bool
+	// graphOnly skips the node, diagnostics and scheduling lookups that do not
+	// add to the relationships, as in the namespace map and --watch frames.
+	graphOnly bool
+	// previous holds the values shown in the last --watch frame, current
+	// collects the ones shown in this frame.
+	previous, current map[string]string
 }
 
 func NewSnifferPlugin(configFlags *genericclioptions.ConfigFlags) (*SnifferPlugin, error) {
@@ -68,14 +96,26 @@ func NewSnifferPlugin(configFlags *genericclioptions.ConfigFlags) (*SnifferPlugi
 		return nil, errors.New("Failed to create API clientset")
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.New("Failed to create dynamic client")
+	}
+
+	return NewSnifferPluginForClients(clientset, dynamicClient), nil
+}
+
+// NewSnifferPluginForClients builds a plugin on top of existing clients, such
+// as the fake clientsets used in tests. dynamicClient may be nil, in which case
+// Gateway API routes are not looked up.
+func NewSnifferPluginForClients(clientset kubernetes.Interface, dynamicClient dynamic.Interface) *SnifferPlugin {
 	return &SnifferPlugin{
-		config:    config,
-		Clientset: clientset,
-	}, nil
+		Clientset:     clientset,
+		DynamicClient: dynamicClient,
+	}
 }
 
-func (sf *SnifferPlugin) findPodByName(name, namespace string) error {
-	pods, err := sf.Clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+func (sf *SnifferPlugin) findPodByName(ctx context.Context, name, namespace string) error {
+	pods, err := sf.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil || len(pods.Items) == 0 {
 		return errors.New("Failed to get pod: [" +
 			name + "], please check your parameters, set a context or verify API server.")
@@ -96,14 +136,17 @@ func (sf *SnifferPlugin) findPodByName(name, namespace string) error {
 	}
 
 	sf.PodObject = &podObj
-	if sf.PodObject.Spec.NodeName == "" || sf.PodObject == nil {
-		return errors.New("Pod is not assigned to a node yet, it's still pending scheduling probably.")
-	}
 	return nil
 }
 
-func (sf *SnifferPlugin) findNodeByName() error {
-	nodeObject, err := sf.Clientset.CoreV1().Nodes().Get(context.TODO(), sf.PodObject.Spec.NodeName, metav1.GetOptions{})
+func (sf *SnifferPlugin) findNodeByName(ctx context.Context) error {
+	if sf.PodObject.Spec.NodeName == "" {
+		return nil
+	}
+	nodeObject, err := sf.Clientset.CoreV1().Nodes().Get(ctx, sf.PodObject.Spec.NodeName, metav1.GetOptions{})
+	if sf.notVisible("nodes", err) {
+		return nil
+	}
 	if err != nil {
 		return errors.New("Failed to get nodes info, verify the connection to their pool.")
 	}
@@ -112,44 +155,68 @@ func (sf *SnifferPlugin) findNodeByName() error {
 	return nil
 }
 
-func (sf *SnifferPlugin) getLabelByPod(labelFlag string) error {
+func (sf *SnifferPlugin) getLabelByPod(labelFlag string, labelKeys []string) error {
 	if labelFlag != "" {
-		match, err := regexp.MatchString("[a-z0-9/-]+=([a-z0-9/-]+)( |$)", labelFlag)
+		selector, err := labels.Parse(labelFlag)
 		if err != nil {
-			return err
+			return errors.Errorf("invalid label selector %q: %v", labelFlag, err)
 		}
-		if match {
-			sf.LabelSelector = labelFlag
+		if selector.Empty() {
+			return errors.Errorf("invalid label selector %q: no requirements", labelFlag)
+		}
+		sf.LabelSelector = selector.String()
+		return nil
+	}
+	if len(labelKeys) == 0 {
+		labelKeys = DefaultLabelKeys
+	}
+	combinations, err := parseLabelKeys(labelKeys)
+	if err != nil {
+		return err
+	}
+	for _, keys := range combinations {
+		set := labels.Set{}
+		for _, key := range keys {
+			value, ok := sf.PodObject.Labels[key]
+			if !ok {
+				set = nil
+				break
+			}
+			set[key] = value
+		}
+		if set != nil {
+			sf.LabelSelector = labels.SelectorFromSet(set).String()
 			return nil
-		} else {
-			return errors.New(labelFlag + " is incorrectly formatted.")
-		}
-	}
-	var labelSelector string
-	l := sf.PodObject.Labels
-	if _, ok := l["app"]; ok {
-		labelSelector = "app=" + l["app"]
-	} else if _, ok = l["release"]; ok {
-		labelSelector = "release=" + l["release"]
-	} else if _, ok = l["k8s-app"]; ok {
-		labelSelector = "k8s-app=" + l["k8s-app"]
-	} else if _, ok = l["app.kubernetes.io/name"]; ok {
-		labelSelector = "app.kubernetes.io/name=" + l["app.kubernetes.io/name"]
-	} else {
-		_, _ = cfmt.Println("Failed to get other, These l do not exist:" +
-			" {{[release]}}::green" +
-			" {{[app]}}::green" +
-			" {{[k8s-app]}}::green" +
-			" {{[app.kubernetes.io/name]}}::green.+" +
-			" So no related resources could be found.")
-	}
-	sf.LabelSelector = labelSelector
+		}
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "None of the label keys %s is set on the pod, so no resources can be found by label.\n",
+		pterm.Green(strings.Join(labelKeys, ", ")))
+	sf.LabelSelector = ""
 	return nil
 }
 
-func (sf *SnifferPlugin) findDeployByLabel(namespace string) error {
+// parseLabelKeys splits label key entries into combinations. An entry is a
+// single key such as "app", or several keys joined with "+" that must all be
+// set on the pod, such as "app.kubernetes.io/name+app.kubernetes.io/instance".
+func parseLabelKeys(entries []string) ([][]string, error) {
+	var combinations [][]string
+	for _, entry := range entries {
+		var keys []string
+		for _, key := range strings.Split(entry, "+") {
+			key = strings.TrimSpace(key)
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return nil, errors.Errorf("invalid label key %q in %q: %s", key, entry, strings.Join(errs, "; "))
+			}
+			keys = append(keys, key)
+		}
+		combinations = append(combinations, keys)
+	}
+	return combinations, nil
+}
+
+func (sf *SnifferPlugin) findDeployByLabel(ctx context.Context, namespace string) error {
 	deployFind, err := sf.Clientset.AppsV1().Deployments(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
 	if err != nil {
 		return err
 	}
@@ -157,9 +224,9 @@ func (sf *SnifferPlugin) findDeployByLabel(namespace string) error {
 	return nil
 }
 
-func (sf *SnifferPlugin) findStsByLabel(namespace string) error {
+func (sf *SnifferPlugin) findStsByLabel(ctx context.Context, namespace string) error {
 	stsFind, err := sf.Clientset.AppsV1().StatefulSets(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
 	if err != nil {
 		return err
 	}
@@ -167,9 +234,9 @@ func (sf *SnifferPlugin) findStsByLabel(namespace string) error {
 	return nil
 }
 
-func (sf *SnifferPlugin) findDsByLabel(namespace string) error {
+func (sf *SnifferPlugin) findDsByLabel(ctx context.Context, namespace string) error {
 	dsFind, err := sf.Clientset.AppsV1().DaemonSets(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
 	if err != nil {
 		return err
 	}
@@ -177,29 +244,61 @@ func (sf *SnifferPlugin) findDsByLabel(namespace string) error {
 	return nil
 }
 
-func (sf *SnifferPlugin) findSvcByLabel(namespace string) error {
+func (sf *SnifferPlugin) findSvcByLabel(ctx context.Context, namespace string) error {
+	svcFind, err := sf.Clientset.CoreV1().Services(namespace).List(
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
+	if err != nil {
+		return err
+	}
+	sf.AllInfo.SvcList = svcFind
+	return nil
+}
+
+func (sf *SnifferPlugin) findSvcBySelector(ctx context.Context, namespace string) error {
 	svcFind, err := sf.Clientset.CoreV1().Services(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
+	podLabels := labels.Set(sf.PodObject.Labels)
+	var items []v1.Service
+	for _, svc := range svcFind.Items {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(podLabels) {
+			items = append(items, svc)
+		}
+	}
+	svcFind.Items = items
 	sf.AllInfo.SvcList = svcFind
 	return nil
 }
 
-func (sf *SnifferPlugin) findIngressByLabel(namespace string) error {
+func (sf *SnifferPlugin) findIngressByBackend(ctx context.Context, namespace string) error {
 	ingFind, err := sf.Clientset.NetworkingV1().Ingresses(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
+	services := sf.serviceNames()
+	var items []netv1.Ingress
+	for _, ing := range ingFind.Items {
+		for _, name := range ingressBackends(ing) {
+			if services[name] {
+				items = append(items, ing)
+				break
+			}
+		}
+	}
+	ingFind.Items = items
 	sf.AllInfo.IngList = ingFind
 	return nil
 }
 
-func (sf *SnifferPlugin) findPVCByLabel(namespace string) error {
+func (sf *SnifferPlugin) findPVCByLabel(ctx context.Context, namespace string) error {
 	pvcFind, err := sf.Clientset.CoreV1().PersistentVolumeClaims(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
 	if err != nil {
 		return err
 	}
@@ -207,9 +306,9 @@ func (sf *SnifferPlugin) findPVCByLabel(namespace string) error {
 	return nil
 }
 
-func (sf *SnifferPlugin) findConfigMapByLabel(namespace string) error {
+func (sf *SnifferPlugin) findConfigMapByLabel(ctx context.Context, namespace string) error {
 	configMapFind, err := sf.Clientset.CoreV1().ConfigMaps(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
 	if err != nil {
 		return err
 	}
@@ -217,9 +316,9 @@ func (sf *SnifferPlugin) findConfigMapByLabel(namespace string) error {
 	return nil
 }
 
-func (sf *SnifferPlugin) findSecretByLabel(namespace string) error {
+func (sf *SnifferPlugin) findSecretByLabel(ctx context.Context, namespace string) error {
 	secretFind, err := sf.Clientset.CoreV1().Secrets(namespace).List(
-		context.TODO(), metav1.ListOptions{LabelSelector: sf.LabelSelector})
+		ctx, metav1.ListOptions{LabelSelector: sf.LabelSelector})
 	if err != nil {
 		return err
 	}
@@ -227,7 +326,7 @@ func (sf *SnifferPlugin) findSecretByLabel(namespace string) error {
 	return nil
 }
 
-func (sf *SnifferPlugin) getOwnerByPod() error {
+func (sf *SnifferPlugin) getOwnerByPod(ctx context.Context) error {
 	for _, existingOwnerRef := range sf.PodObject.GetOwnerReferences() {
 		ownerKind := strings.ToLower(existingOwnerRef.Kind)
 		var status bool
@@ -235,9 +334,13 @@ func (sf *SnifferPlugin) getOwnerByPod() error {
 		case "replicaset":
 			rsObject, err := sf.Clientset.AppsV1().ReplicaSets(
 				sf.PodObject.GetNamespace()).Get(
-				context.TODO(),
+				ctx,
 				existingOwnerRef.Name,
 				metav1.GetOptions{})
+			if sf.notVisible("replicasets", err) {
+				sf.AllInfo.Workload = Workload{Name: existingOwnerRef.Name, Type: ownerKind, Replicas: "?"}
+				continue
+			}
 			if err != nil {
 				return errors.New("Failed to retrieve replica set data, AppsV1 API was not available.")
 			}
@@ -256,9 +359,13 @@ func (sf *SnifferPlugin) getOwnerByPod() error {
 		case "statefulset":
 			ssObject, err := sf.Clientset.AppsV1().StatefulSets(
 				sf.PodObject.GetNamespace()).Get(
-				context.TODO(),
+				ctx,
 				existingOwnerRef.Name,
 				metav1.GetOptions{})
+			if sf.notVisible("statefulsets", err) {
+				sf.AllInfo.Workload = Workload{Name: existingOwnerRef.Name, Type: ownerKind, Replicas: "?"}
+				continue
+			}
 			if err != nil {
 				return errors.New("Failed to retrieve stateful set data, AppsV1 API was not available.")
 			}
@@ -274,9 +381,13 @@ func (sf *SnifferPlugin) getOwnerByPod() error {
 		case "daemonset":
 			dsObject, err := sf.Clientset.AppsV1().DaemonSets(
 				sf.PodObject.GetNamespace()).Get(
-				context.TODO(),
+				ctx,
 				existingOwnerRef.Name,
 				metav1.GetOptions{})
+			if sf.notVisible("daemonsets", err) {
+				sf.AllInfo.Workload = Workload{Name: existingOwnerRef.Name, Type: ownerKind, Replicas: "?"}
+				continue
+			}
 			if err != nil {
 				return errors.New("Failed to retrieve daemon set data, AppsV1 API was not available.")
 			}
@@ -300,25 +411,25 @@ func (sf *SnifferPlugin) getOwnerByPod() error {
 	return nil
 }
 
-func (sf *SnifferPlugin) findHpaByName(namespace string) error {
+func (sf *SnifferPlugin) findHpaByName(ctx context.Context, namespace string) error {
 	hpaFind, err := sf.Clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(
-		context.TODO(),
+		ctx,
 		metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
 	for _, hpa := range hpaFind.Items {
 		if hpa.Spec.ScaleTargetRef.Name == sf.AllInfo.Workload.Name {
-			sf.AllInfo.Hpa = &hpa
+			sf.AllInfo.Hpa = hpa.DeepCopy()
 		}
 	}
 	return nil
 }
 
-func (sf *SnifferPlugin) findPdbByName(namespace string) error {
+func (sf *SnifferPlugin) findPdbByName(ctx context.Context, namespace string) error {
 
 	pdbFind, err := sf.Clientset.PolicyV1().PodDisruptionBudgets(namespace).List(
-		context.TODO(),
+		ctx,
 		metav1.ListOptions{})
 	if err != nil {
 		return err
@@ -329,13 +440,23 @@ func (sf *SnifferPlugin) findPdbByName(namespace string) error {
 			return err
 		}
 		if selector.Empty() || selector.Matches(labels.Set(sf.PodObject.Labels)) {
-			sf.AllInfo.Pdbs = append(sf.AllInfo.Pdbs, &pdb)
+			sf.AllInfo.Pdbs = append(sf.AllInfo.Pdbs, pdb.DeepCopy())
 		}
 	}
 	return nil
 }
 
 func (sf *SnifferPlugin) printPodLeveledList() error {
+	pterm.Println(sf.podPanel())
+	return nil
+}
+
+// podPanel renders the tree of the pod, or of the workload and its pods, next
+// to their state.
+func (sf *SnifferPlugin) podPanel() string {
+	if sf.fromWorkload {
+		return sf.workloadPanel()
+	}
 	var leveledList pterm.LeveledList
 	var stateList string
 	cfmt.RegisterStyle("pod", func(s string) string {
@@ -349,6 +470,9 @@ func (sf *SnifferPlugin) printPodLeveledList() error {
 	})
 	leveledList = append(leveledList, pterm.LeveledListItem{Level: 0,
 		Text: cfmt.Sprintf("{{ [Namespace] }}::cyan|bold %s", sf.PodObject.Namespace)})
+	if sf.LabelSelector != "" {
+		stateList += "Selector: " + pterm.Cyan(sf.LabelSelector)
+	}
 	stateList += "\n"
 	leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
 		Text: cfmt.Sprintf("{{ [%s] }}::lightBlue|bold %s",
@@ -358,26 +482,39 @@ func (sf *SnifferPlugin) printPodLeveledList() error {
 			return cfmt.Sprintf("{{%s}}::red|bold", s)
 		})
 	}
-	stateList += cfmt.Sprintf("Replica: {{%s}}::replica\n",
-		sf.AllInfo.Workload.Replicas)
-	leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
-		Text: cfmt.Sprintf("{{ [Node] }}::magenta|bold %s", sf.PodObject.Spec.NodeName)})
-	var nodeIp string
-	for _, ip := range sf.AllInfo.Node.Status.Addresses {
-		if ip.Type == "InternalIP" {
-			nodeIp = cfmt.Sprintf("Node IP: {{%s}}::magenta", ip.Address)
+	stateList += cfmt.Sprintf("Replica: {{%s}}::replica%s\n",
+		sf.AllInfo.Workload.Replicas, sf.was("replica", sf.AllInfo.Workload.Replicas))
+	if sf.AllInfo.Node == nil && sf.PodObject.Spec.NodeName != "" {
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
+			Text: cfmt.Sprintf("{{ [Node] }}::magenta|bold %s", sf.PodObject.Spec.NodeName)})
+		stateList += cfmt.Sprintf("{{(not visible)}}::yellow\n")
+	} else if sf.AllInfo.Node == nil {
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
+			Text: cfmt.Sprintf("{{ [Node] }}::magenta|bold {{<not scheduled>}}::red")})
+		stateList += cfmt.Sprintf("{{[Unscheduled]}}::red|bold see Scheduling Analysis\n")
+	} else {
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
+			Text: cfmt.Sprintf("{{ [Node] }}::magenta|bold %s", sf.PodObject.Spec.NodeName)})
+		var nodeIp string
+		for _, ip := range sf.AllInfo.Node.Status.Addresses {
+			if ip.Type == "InternalIP" {
+				nodeIp = cfmt.Sprintf("Node IP: {{%s}}::magenta", ip.Address)
+			}
 		}
-	}
-	var nodeStatus v1.NodeConditionType = "Ready"
-	for _, s := range sf.AllInfo.Node.Status.Conditions {
-		if s.Status == "True" && s.Type != "Ready" {
-			nodeStatus = s.Type
-			cfmt.RegisterStyle("pod", func(s string) string {
-				return cfmt.Sprintf("{{%s}}::red|bold", s)
-			})
+		var nodeStatus v1.NodeConditionType = "Ready"
+		for _, s := range sf.AllInfo.Node.Status.Conditions {
+			if (s.Status == "True") != (s.Type == "Ready") {
+				nodeStatus = s.Type
+				if s.Type == "Ready" {
+					nodeStatus = "NotReady"
+				}
+				cfmt.RegisterStyle("pod", func(s string) string {
+					return cfmt.Sprintf("{{%s}}::red|bold", s)
+				})
+			}
 		}
+		stateList += cfmt.Sprintf("{{[%s]}}::pod%s %s\n", nodeStatus, sf.was("node", string(nodeStatus)), nodeIp)
 	}
-	stateList += cfmt.Sprintf("{{[%s]}}::pod %s\n", nodeStatus, nodeIp)
 	if sf.PodObject.Status.Phase != "Running" && sf.PodObject.Status.Phase != "Succeeded" {
 		cfmt.RegisterStyle("pod", func(s string) string {
 			return cfmt.Sprintf("{{%s}}::red|bold", s)
@@ -385,10 +522,11 @@ func (sf *SnifferPlugin) printPodLeveledList() error {
 	}
 	podInfo := cfmt.Sprintf("{{ [Pod] }}::blue|bold %s", sf.PodObject.Name)
 	leveledList = append(leveledList, pterm.LeveledListItem{Level: 3, Text: podInfo})
-	stateList += cfmt.Sprintf("{{[%s]}}::pod Pod IP: {{%s}}::magenta\n",
-		sf.PodObject.Status.Phase, sf.PodObject.Status.PodIP)
+	stateList += cfmt.Sprintf("{{[%s]}}::pod%s Pod IP: {{%s}}::magenta\n",
+		sf.PodObject.Status.Phase, sf.was("phase", string(sf.PodObject.Status.Phase)), sf.PodObject.Status.PodIP)
 	for _, val := range sf.PodObject.Status.InitContainerStatuses {
-		if val.State.Terminated.Reason != "Completed" {
+		state := containerState(val.State)
+		if state != "Completed" {
 			cfmt.RegisterStyle("pod", func(s string) string {
 				return cfmt.Sprintf("{{%s}}::red|bold", s)
 			})
@@ -400,8 +538,15 @@ func (sf *SnifferPlugin) printPodLeveledList() error {
 		}
 		initInfo := cfmt.Sprintf("{{ [initContainer] }}::gray|bold %s", val.Name)
 		leveledList = append(leveledList, pterm.LeveledListItem{Level: 4, Text: initInfo})
-		stateList += cfmt.Sprintf("{{[%s]}}::pod Restart: {{%d}}::restart\n",
-			val.State.Terminated.Reason, val.RestartCount)
+		stateList += cfmt.Sprintf("{{[%s]}}::pod%s Restart: {{%d}}::restart%s\n",
+			state, sf.was("container/"+val.Name+"/state", state),
+			val.RestartCount, sf.was("container/"+val.Name+"/restarts", fmt.Sprint(val.RestartCount)))
+		diagItems, diagState := sf.diagnosisLeveledList(val.Name)
+		leveledList = append(leveledList, diagItems...)
+		stateList += diagState
+		logItems, logState := sf.logsLeveledList(val.Name)
+		leveledList = append(leveledList, logItems...)
+		stateList += logState
 	}
 	for _, val := range sf.PodObject.Status.ContainerStatuses {
 		state := "Running"
@@ -426,53 +571,26 @@ func (sf *SnifferPlugin) printPodLeveledList() error {
 		}
 		containerInfo := cfmt.Sprintf("{{ [Container] }}::lightGreen|bold %s", val.Name)
 		leveledList = append(leveledList, pterm.LeveledListItem{Level: 4, Text: containerInfo})
-		stateList += cfmt.Sprintf("{{[%s]}}::pod Restart: {{%d}}::restart\n",
-			state, val.RestartCount)
-	}
-	for _, val := range sf.PodObject.Spec.Volumes {
-		secretList := mapset.NewSet()
-		configmapList := mapset.NewSet()
-		pvcList := mapset.NewSet()
-		if val.Projected != nil {
-			for _, i := range val.Projected.Sources {
-				if i.Secret != nil {
-					secretList.Add(i.Secret.Name)
-				}
-				if i.ConfigMap != nil {
-					configmapList.Add(i.ConfigMap.Name)
-				}
-			}
-		}
-		if val.ConfigMap != nil {
-			configmapList.Add(val.ConfigMap.Name)
-		}
-		if val.Secret != nil {
-			secretList.Add(val.Secret.SecretName)
-		}
-		if val.PersistentVolumeClaim != nil {
-			pvcList.Add(val.PersistentVolumeClaim.ClaimName)
-		}
-		for _, p := range pvcList.ToSlice() {
-			leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
-				Text: cfmt.Sprintf("{{ [PVC] }}::yellow|bold %s", p)})
-		}
-		for _, c := range configmapList.ToSlice() {
-			leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
-				Text: cfmt.Sprintf("{{ [ConfigMap] }}::lightMagenta|bold %s", c)})
-		}
-		for _, s := range secretList.ToSlice() {
-			leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
-				Text: cfmt.Sprintf("{{ [Secret] }}::red|bold %s", s)})
-		}
+		stateList += cfmt.Sprintf("{{[%s]}}::pod%s Restart: {{%d}}::restart%s\n",
+			state, sf.was("container/"+val.Name+"/state", state),
+			val.RestartCount, sf.was("container/"+val.Name+"/restarts", fmt.Sprint(val.RestartCount)))
+		diagItems, diagState := sf.diagnosisLeveledList(val.Name)
+		leveledList = append(leveledList, diagItems...)
+		stateList += diagState
+		logItems, logState := sf.logsLeveledList(val.Name)
+		leveledList = append(leveledList, logItems...)
+		stateList += logState
 	}
+	leveledList = append(leveledList, sf.referenceLeveledList()...)
+	leveledList = append(leveledList, sf.trafficLeveledList()...)
 	root := pterm.NewTreeFromLeveledList(leveledList)
 	tree, _ := pterm.DefaultTree.WithRoot(root).Srender()
 
 	panels := pterm.Panels{
 		{{Data: tree}, {Data: stateList}},
 	}
-	_ = pterm.DefaultPanel.WithPanels(panels).WithPadding(5).Render()
-	return nil
+	panel, _ := pterm.DefaultPanel.WithPanels(panels).WithPadding(5).Srender()
+	return panel
 }
 
 func (sf *SnifferPlugin) printResource() error {
@@ -485,111 +603,156 @@ func (sf *SnifferPlugin) printResource() error {
 	cfmt.RegisterStyle("url", func(s string) string {
 		return cfmt.Sprintf("{{%s}}::yellow|underline", s)
 	})
-	for _, deploy := range sf.AllInfo.DeployList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{Deployment}}::lightBlue"))
-		table.AddRow("Name:", deploy.Name)
-		table.AddRow("Replicas:", cfmt.Sprintf("{{%d}}::yellow", deploy.Status.Replicas))
-		table.AddRow("---", "---")
+	if sf.AllInfo.DeployList != nil {
+		for _, deploy := range sf.AllInfo.DeployList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{Deployment}}::lightBlue"))
+			table.AddRow("Name:", deploy.Name)
+			table.AddRow("Replicas:", cfmt.Sprintf("{{%d}}::yellow", deploy.Status.Replicas))
+			table.AddRow("---", "---")
+		}
 	}
 
-	for _, sts := range sf.AllInfo.StsList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{StatefulSet}}::lightBlue"))
-		table.AddRow("Name:", sts.Name)
-		table.AddRow("Replicas:", cfmt.Sprintf("{{%d}}::yellow", sts.Status.Replicas))
-		table.AddRow("---", "---")
+	if sf.AllInfo.StsList != nil {
+		for _, sts := range sf.AllInfo.StsList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{StatefulSet}}::lightBlue"))
+			table.AddRow("Name:", sts.Name)
+			table.AddRow("Replicas:", cfmt.Sprintf("{{%d}}::yellow", sts.Status.Replicas))
+			table.AddRow("---", "---")
+		}
 	}
 
-	for _, ds := range sf.AllInfo.DsList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{DaemonSet}}::lightBlue"))
-		table.AddRow("Name:", ds.Name)
-		table.AddRow("Replicas:", cfmt.Sprintf("{{%d}}::yellow", ds.Status.DesiredNumberScheduled))
-		table.AddRow("---", "---")
+	if sf.AllInfo.DsList != nil {
+		for _, ds := range sf.AllInfo.DsList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{DaemonSet}}::lightBlue"))
+			table.AddRow("Name:", ds.Name)
+			table.AddRow("Replicas:", cfmt.Sprintf("{{%d}}::yellow", ds.Status.DesiredNumberScheduled))
+			table.AddRow("---", "---")
+		}
 	}
 
-	for _, svc := range sf.AllInfo.SvcList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{Service}}::lightYellow"))
-		table.AddRow("Name:", svc.Name)
-		if svc.Spec.ClusterIP != "None" {
-			table.AddRow("Cluster IP:", cfmt.Sprintf("{{%s}}::yellow", svc.Spec.ClusterIP))
-		}
-		var ports string
-		table.AddRow("Ports", "")
-		for _, v := range svc.Spec.Ports {
-			if v.TargetPort.IntVal == 0 {
-				ports = cfmt.Sprintf("---\nName: {{%s}}::yellow\nPort: {{%d}}::yellow\nTargetPort: {{%s}}::yellow",
-					v.Name, v.Port, v.TargetPort.StrVal)
-			} else {
-				ports = cfmt.Sprintf("---\nName: {{%s}}::yellow\nPort: {{%d}}::yellow\nTargetPort: {{%d}}::yellow",
-					v.Name, v.Port, v.TargetPort.IntVal)
+	if sf.AllInfo.SvcList != nil {
+		for _, svc := range sf.AllInfo.SvcList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{Service}}::lightYellow"))
+			table.AddRow("Name:", svc.Name)
+			if len(svc.Spec.Selector) > 0 {
+				table.AddRow("Selector:", cfmt.Sprintf("{{%s}}::yellow",
+					labels.SelectorFromSet(svc.Spec.Selector).String()))
 			}
-			table.AddRow("", ports)
-		}
-		for _, ing := range svc.Status.LoadBalancer.Ingress {
-			if ing.IP != "" {
-				table.AddRow("IP:", cfmt.Sprintf("{{%s}}::url", ing.IP))
+			if svc.Spec.ClusterIP != "None" {
+				table.AddRow("Cluster IP:", cfmt.Sprintf("{{%s}}::yellow", svc.Spec.ClusterIP))
 			}
-			if ing.Hostname != "" {
-				table.AddRow("Host:", cfmt.Sprintf("{{%s}}::url", ing.Hostname))
+			var ports string
+			table.AddRow("Ports", "")
+			for _, v := range svc.Spec.Ports {
+				if v.TargetPort.IntVal == 0 {
+					ports = cfmt.Sprintf("---\nName: {{%s}}::yellow\nPort: {{%d}}::yellow\nTargetPort: {{%s}}::yellow",
+						v.Name, v.Port, v.TargetPort.StrVal)
+				} else {
+					ports = cfmt.Sprintf("---\nName: {{%s}}::yellow\nPort: {{%d}}::yellow\nTargetPort: {{%d}}::yellow",
+						v.Name, v.Port, v.TargetPort.IntVal)
+				}
+				table.AddRow("", ports)
 			}
+			for _, ing := range svc.Status.LoadBalancer.Ingress {
+				if ing.IP != "" {
+					table.AddRow("IP:", cfmt.Sprintf("{{%s}}::url", ing.IP))
+				}
+				if ing.Hostname != "" {
+					table.AddRow("Host:", cfmt.Sprintf("{{%s}}::url", ing.Hostname))
+				}
 
+			}
+			table.AddRow("---", "---")
 		}
-		table.AddRow("---", "---")
 	}
 
-	for _, ing := range sf.AllInfo.IngList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{Ingress}}::green"))
-		table.AddRow("Name:", ing.Name)
-		for _, r := range ing.Spec.Rules {
-			for _, p := range r.IngressRuleValue.HTTP.Paths {
-				table.AddRow("Url:", cfmt.Sprintf("{{https://%s%s}}::url",
-					r.Host, p.Path))
-				table.AddRow("Backend:", p.Backend.Service.Name)
+	if sf.AllInfo.IngList != nil {
+		for _, ing := range sf.AllInfo.IngList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{Ingress}}::green"))
+			table.AddRow("Name:", ing.Name)
+			for _, r := range ing.Spec.Rules {
+				if r.IngressRuleValue.HTTP == nil {
+					continue
+				}
+				for _, p := range r.IngressRuleValue.HTTP.Paths {
+					table.AddRow("Url:", cfmt.Sprintf("{{https://%s%s}}::url",
+						r.Host, p.Path))
+					if p.Backend.Service != nil {
+						table.AddRow("Backend:", p.Backend.Service.Name)
+					}
+				}
 			}
-		}
-		var loadBalancesList string
-		for _, i := range ing.Status.LoadBalancer.Ingress {
-			if i.IP != "" {
-				loadBalancesList += cfmt.Sprintf("\n{{%s}}::lightGreen", i.IP)
+			if ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+				table.AddRow("Default Backend:", ing.Spec.DefaultBackend.Service.Name)
 			}
-			if i.Hostname != "" {
-				loadBalancesList += cfmt.Sprintf("\n{{%s}}::lightGreen", i.Hostname)
+			var loadBalancesList string
+			for _, i := range ing.Status.LoadBalancer.Ingress {
+				if i.IP != "" {
+					loadBalancesList += cfmt.Sprintf("\n{{%s}}::lightGreen", i.IP)
+				}
+				if i.Hostname != "" {
+					loadBalancesList += cfmt.Sprintf("\n{{%s}}::lightGreen", i.Hostname)
+				}
 			}
+			table.AddRow("LoadBalance IP:", loadBalancesList)
+			table.AddRow("---")
 		}
-		table.AddRow("LoadBalance IP:", loadBalancesList)
-		table.AddRow("---")
-	}
-
-	for _, pvc := range sf.AllInfo.PvcList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{PVC}}::gray"))
-		table.AddRow("Name:", pvc.Name)
-		table.AddRow("Storage Class:", cfmt.Sprintf("{{%s}}::lightGreen",
-			*pvc.Spec.StorageClassName))
-		table.AddRow("Access Modes:", cfmt.Sprintf("{{%s}}::lightGreen",
-			string(pvc.Spec.AccessModes[0])))
-		pvcSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
-		table.AddRow("Size:", cfmt.Sprintf("{{%s}}::lightGreen",
-			pvcSize.String()))
-		table.AddRow("PV Name:", pvc.Spec.VolumeName)
-		table.AddRow("---", "---")
 	}
 
-	for _, conf := range sf.AllInfo.ConfigMapList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{ConfigMap}}::magenta"))
-		table.AddRow("Name:", conf.Name)
+	for _, route := range sf.AllInfo.Routes {
+		table.AddRow("Kind:", cfmt.Sprintf("{{%s}}::green", route.Kind))
+		table.AddRow("Name:", route.Name)
+		for _, h := range route.Hostnames {
+			table.AddRow("Host:", cfmt.Sprintf("{{%s}}::url", h))
+		}
+		for _, p := range route.Parents {
+			table.AddRow("Parent:", fmt.Sprintf("%s %s/%s", p.Kind, p.Namespace, p.Name))
+		}
+		table.AddRow("Backend:", strings.Join(route.Backends, ", "))
 		table.AddRow("---", "---")
 	}
 
-	for _, sec := range sf.AllInfo.SecretList.Items {
-		table.AddRow("Kind:", cfmt.Sprintf("{{Secrets}}::red"))
-		table.AddRow("Name:", sec.Name)
-		table.AddRow("---", "---")
+	if sf.AllInfo.PvcList != nil {
+		for _, pvc := range sf.AllInfo.PvcList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{PVC}}::gray"))
+			table.AddRow("Name:", pvc.Name)
+			if pvc.Spec.StorageClassName != nil {
+				table.AddRow("Storage Class:", cfmt.Sprintf("{{%s}}::lightGreen",
+					*pvc.Spec.StorageClassName))
+			}
+			if len(pvc.Spec.AccessModes) > 0 {
+				table.AddRow("Access Modes:", cfmt.Sprintf("{{%s}}::lightGreen",
+					accessModes(pvc.Spec.AccessModes)))
+			}
+			pvcSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+			table.AddRow("Size:", cfmt.Sprintf("{{%s}}::lightGreen",
+				pvcSize.String()))
+			table.AddRow("PV Name:", pvc.Spec.VolumeName)
+			table.AddRow("---", "---")
+		}
+	}
+
+	if sf.AllInfo.ConfigMapList != nil {
+		for _, conf := range sf.AllInfo.ConfigMapList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{ConfigMap}}::magenta"))
+			table.AddRow("Name:", conf.Name)
+			table.AddRow("---", "---")
+		}
+	}
+
+	if sf.AllInfo.SecretList != nil {
+		for _, sec := range sf.AllInfo.SecretList.Items {
+			table.AddRow("Kind:", cfmt.Sprintf("{{Secrets}}::red"))
+			table.AddRow("Name:", sec.Name)
+			table.AddRow("---", "---")
+		}
 	}
 
 	if sf.AllInfo.Hpa != nil {
 		table.AddRow("Kind:", cfmt.Sprintf("{{HPA}}::cyan"))
 		table.AddRow("Name:", sf.AllInfo.Hpa.Name)
 		table.AddRow("MIN:", cfmt.Sprintf("{{%d}}::lightGreen",
-			*sf.AllInfo.Hpa.Spec.MinReplicas))
+			replicaCount(sf.AllInfo.Hpa.Spec.MinReplicas)))
 		table.AddRow("MAX:", cfmt.Sprintf("{{%d}}::lightGreen",
 			sf.AllInfo.Hpa.Spec.MaxReplicas))
 		table.AddRow("---", "---")
@@ -618,84 +781,271 @@ func (sf *SnifferPlugin) printResource() error {
 	return nil
 }
 
-func RunPlugin(configFlags *genericclioptions.ConfigFlags, outputCh chan string, allNamespacesFlag bool, labelFlag string) error {
+// DefaultLabelKeys are tried in order when neither -l nor label-keys is set.
+var DefaultLabelKeys = []string{"app", "release", "k8s-app", "app.kubernetes.io/name"}
+
+type Options struct {
+	AllNamespaces  bool
+	CheckAccess    bool
+	FromDirs       []string
+	FromFiles      []string
+	LabelKeys      []string
+	LabelSelector  string
+	Logs           int
+	LogsGrep       string
+	Output         string
+	Redact         []string
+	Report         string
+	Resources      []string
+	ServiceByLabel bool
+	Strict         bool
+	TUI            bool
+	Watch          bool
+}
+
+func RunPlugin(configFlags *genericclioptions.ConfigFlags, outputCh chan string, opts Options) error {
 	klog.V(1).Info("start run plugins")
-	sf, err := NewSnifferPlugin(configFlags)
-	if err != nil {
+	if err := validateOutput(opts.Output); err != nil {
 		return err
 	}
 
-	podName := <-outputCh
-	var namespace string
-	if !allNamespacesFlag {
-		namespace = getNamespace(configFlags)
+	if opts.offline() && opts.CheckAccess {
+		return errors.New("--check-access needs a cluster and cannot be used with --from-file or --from-dir")
 	}
-
-	if err = sf.findPodByName(podName, namespace); err != nil {
-		return err
+	if opts.offline() && opts.Watch {
+		return errors.New("--watch needs a cluster and cannot be used with --from-file or --from-dir")
 	}
-
-	if err = sf.findNodeByName(); err != nil {
-		return err
+	if opts.Watch && (opts.Output != "" || opts.Report != "" || opts.Strict || opts.CheckAccess || opts.Logs != 0) {
+		return errors.New("--watch cannot be combined with -o, --report, --strict, --check-access or --logs")
 	}
-
-	if err = sf.getOwnerByPod(); err != nil {
-		return err
+	if opts.TUI && (opts.Output != "" || opts.Report != "" || opts.Strict || opts.CheckAccess || opts.Watch || opts.Logs != 0) {
+		return errors.New("--tui cannot be combined with -o, --report, --strict, --check-access, --watch or --logs")
+	}
+	if opts.LogsGrep != "" && opts.Logs == 0 {
+		opts.Logs = DefaultLogLines
+	}
+	if opts.Logs < 0 {
+		return errors.New("--logs must be a positive number of lines")
+	}
+	if opts.offline() && opts.Logs > 0 {
+		return errors.New("--logs needs a cluster and cannot be used with --from-file or --from-dir")
 	}
 
-	if err = sf.printPodLeveledList(); err != nil {
+	sf, err := newPluginForOptions(configFlags, opts)
+	if err != nil {
 		return err
 	}
+	if opts.LogsGrep != "" {
+		if sf.logGrep, err = regexp.Compile(opts.LogsGrep); err != nil {
+			return errors.Wrap(err, "invalid --logs-grep")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err = sf.getLabelByPod(labelFlag); err != nil {
+	timeout, err := requestTimeout(configFlags)
+	if err != nil {
 		return err
 	}
 
-	if err = sf.findDeployByLabel(sf.PodObject.Namespace); err != nil {
+	kind, name, err := parseTarget(<-outputCh)
+	if err != nil {
 		return err
 	}
+	namespace := lookupNamespace(configFlags, opts)
+	if kind != "" && opts.Logs > 0 {
+		return errors.Errorf("--logs shows the containers of one pod and is not supported for %s/<name>", strings.ToLower(kind))
+	}
+	switch kind {
+	case "Node":
+		return sf.runNodeLens(ctx, timeout, name, opts)
+	case "Namespace":
+		return sf.runNamespaceLens(ctx, timeout, name, opts)
+	}
 
-	if err = sf.findStsByLabel(sf.PodObject.Namespace); err != nil {
-		return err
+	if kind != "" {
+		err = sf.findWorkload(ctx, kind, name, namespace)
+	} else {
+		err = sf.findPodByName(ctx, name, namespace)
+	}
+	if err != nil {
+		return contextError(ctx, timeout, err)
 	}
 
-	if err = sf.findDsByLabel(sf.PodObject.Namespace); err != nil {
+	if err = sf.getLabelByPod(opts.LabelSelector, opts.LabelKeys); err != nil {
 		return err
 	}
+	if opts.Watch {
+		return sf.runWatch(ctx, timeout, kind, opts)
+	}
+	if opts.TUI {
+		var describe func(context.Context, ObjectRef) (string, error)
+		if !opts.offline() {
+			describe = kubectlDescribe(configFlags)
+		}
+		return sf.runTUI(ctx, timeout, opts, describe)
+	}
 
-	if err = sf.findSvcByLabel(sf.PodObject.Namespace); err != nil {
-		return err
+	// The deadline starts once the pod is selected, so an interactive pick
+	// does not eat into the time budget for the lookups.
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if opts.CheckAccess {
+		denied, err := sf.checkAccess(ctx, sf.PodObject.Namespace)
+		if err != nil {
+			return contextError(ctx, timeout, errors.Wrap(err, "failed to check access"))
+		}
+		printAccessCheck(sf.PodObject.Namespace, denied)
+	}
+	if err = sf.findRelated(ctx, opts); err != nil {
+		return contextError(ctx, timeout, err)
 	}
 
-	if err = sf.findIngressByLabel(sf.PodObject.Namespace); err != nil {
-		return err
+	if opts.Report != "" {
+		if err = sf.writeReportFile(opts.Report); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Report written to %s\n", opts.Report)
 	}
 
-	if err = sf.findPVCByLabel(sf.PodObject.Namespace); err != nil {
-		return err
+	if opts.Output != "" {
+		if err = sf.printOutput(os.Stdout, opts.Output); err != nil {
+			return err
+		}
+	} else {
+		if err = sf.printPodLeveledList(); err != nil {
+			return err
+		}
+
+		if err = sf.printResource(); err != nil {
+			return err
+		}
+
+		sf.printEvents()
+		sf.printScheduling()
+		sf.printNotVisible()
 	}
 
-	if err = sf.findConfigMapByLabel(sf.PodObject.Namespace); err != nil {
-		return err
+	if opts.Strict {
+		if dangling := sf.danglingReferences(); len(dangling) > 0 {
+			return errors.Errorf("found %d dangling reference(s)", len(dangling))
+		}
 	}
 
-	if err = sf.findSecretByLabel(sf.PodObject.Namespace); err != nil {
+	return nil
+}
+
+// findRelated looks up everything related to the selected pod. Lookups that
+// only depend on the pod run in parallel; the ones needing their results
+// follow in later phases.
+func (sf *SnifferPlugin) findRelated(ctx context.Context, opts Options) error {
+	namespace := sf.PodObject.Namespace
+	var tasks []task
+	if !sf.fromWorkload && !sf.graphOnly {
+		tasks = append(tasks, sf.findNodeByName)
+	}
+	if !sf.fromWorkload {
+		tasks = append(tasks, sf.getOwnerByPod)
+	}
+	if opts.Logs > 0 && !sf.fromWorkload && !sf.graphOnly {
+		tasks = append(tasks, func(ctx context.Context) error { return sf.findLogs(ctx, opts.Logs) })
+	}
+	tasks = append(tasks,
+		sf.lookup("services", func(ctx context.Context) error {
+			if opts.ServiceByLabel {
+				if sf.LabelSelector == "" {
+					return nil
+				}
+				return sf.findSvcByLabel(ctx, namespace)
+			}
+			return sf.findSvcBySelector(ctx, namespace)
+		}),
+		sf.ifEnabled("references", func(ctx context.Context) error {
+			if err := sf.findReferences(ctx, namespace); err != nil {
+				return err
+			}
+			return sf.resolveReferences(ctx, namespace)
+		}),
+		sf.lookup("poddisruptionbudgets", func(ctx context.Context) error { return sf.findPdbByName(ctx, namespace) }),
+	)
+	// An empty selector would match every object in the namespace, so the
+	// label based lookups only run when a selector is known.
+	if sf.LabelSelector != "" {
+		tasks = append(tasks,
+			sf.lookup("deployments", func(ctx context.Context) error { return sf.findDeployByLabel(ctx, namespace) }),
+			sf.lookup("statefulsets", func(ctx context.Context) error { return sf.findStsByLabel(ctx, namespace) }),
+			sf.lookup("daemonsets", func(ctx context.Context) error { return sf.findDsByLabel(ctx, namespace) }),
+			sf.lookup("persistentvolumeclaims", func(ctx context.Context) error { return sf.findPVCByLabel(ctx, namespace) }),
+			sf.lookup("configmaps", func(ctx context.Context) error { return sf.findConfigMapByLabel(ctx, namespace) }),
+			sf.lookup("secrets", func(ctx context.Context) error { return sf.findSecretByLabel(ctx, namespace) }),
+		)
+	}
+	err := runConcurrently(ctx, tasks...)
+	if err != nil {
 		return err
 	}
 
-	if err = sf.findHpaByName(sf.PodObject.Namespace); err != nil {
+	err = runConcurrently(ctx,
+		sf.lookup("ingresses", func(ctx context.Context) error { return sf.findIngressByBackend(ctx, namespace) }),
+		sf.ifEnabled("routes", func(ctx context.Context) error { return sf.findRoutesByBackend(ctx, namespace) }),
+		sf.lookup("horizontalpodautoscalers", func(ctx context.Context) error { return sf.findHpaByName(ctx, namespace) }),
+		sf.ifEnabled("events", sf.findEvents),
+	)
+	if err != nil {
 		return err
 	}
 
-	if err = sf.findPdbByName(sf.PodObject.Namespace); err != nil {
-		return err
+	// The template pod has no status to diagnose and is never scheduled, the
+	// state of the real pods is summarized instead.
+	if sf.fromWorkload || sf.graphOnly {
+		return nil
 	}
+	sf.AllInfo.Diagnostics = sf.diagnoseContainers()
+	return sf.analyzeScheduling(ctx, namespace)
+}
 
-	if err = sf.printResource(); err != nil {
-		return err
+func (opts Options) offline() bool {
+	return len(opts.FromFiles) > 0 || len(opts.FromDirs) > 0
+}
+
+// newPluginForOptions reads from the cluster, or from manifests in offline mode.
+func newPluginForOptions(configFlags *genericclioptions.ConfigFlags, opts Options) (*SnifferPlugin, error) {
+	var (
+		sf  *SnifferPlugin
+		err error
+	)
+	if opts.offline() {
+		sf, err = NewOfflineSnifferPlugin(opts.FromFiles, opts.FromDirs)
+	} else {
+		sf, err = NewSnifferPlugin(configFlags)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = sf.setResources(opts.Resources); err != nil {
+		return nil, err
 	}
+	if err = sf.setRedactRules(opts.Redact); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
 
-	return nil
+// lookupNamespace returns the namespace to search, empty for all namespaces.
+func lookupNamespace(configFlags *genericclioptions.ConfigFlags, opts Options) string {
+	if opts.AllNamespaces {
+		return ""
+	}
+	if opts.offline() {
+		// Dumps usually cover several namespaces and the kubeconfig context
+		// says nothing about them, so only an explicit -n narrows the search.
+		return *configFlags.Namespace
+	}
+	return getNamespace(configFlags)
 }
 
 func getNamespace(configFlags *genericclioptions.ConfigFlags) string {
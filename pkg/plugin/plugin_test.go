@@ -0,0 +1,569 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autov1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "default"
+
+func newTestPlugin(objects ...runtime.Object) (*SnifferPlugin, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	return NewSnifferPluginForClients(clientset, nil), clientset
+}
+
+func testPod() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-7d4b9-abcde",
+			Namespace: testNamespace,
+			Labels:    map[string]string{"app": "web", "tier": "frontend"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web-7d4b9"},
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []v1.Container{{Name: "app", Image: "nginx"}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func forbid(clientset *fake.Clientset, verb, resource string) {
+	clientset.PrependReactor(verb, resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: resource}, "", nil)
+	})
+}
+
+func objectMeta(name string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: labels}
+}
+
+func TestFindPodByName(t *testing.T) {
+	other := testPod()
+	other.Name = "db-0"
+	elsewhere := testPod()
+	elsewhere.Namespace = "kube-system"
+	elsewhere.Name = "coredns-1"
+
+	tests := []struct {
+		name      string
+		query     string
+		namespace string
+		want      string
+		wantErr   bool
+	}{
+		{name: "exact name", query: "web-7d4b9-abcde", namespace: testNamespace, want: "web-7d4b9-abcde"},
+		{name: "prefix with wildcard", query: "db*", namespace: testNamespace, want: "db-0"},
+		{name: "all namespaces", query: "coredns", namespace: "", want: "coredns-1"},
+		{name: "no match", query: "cache", namespace: testNamespace, wantErr: true},
+		{name: "other namespace", query: "coredns", namespace: testNamespace, wantErr: true},
+		{name: "empty namespace", query: "web", namespace: "monitoring", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(testPod(), other, elsewhere)
+			err := sf.findPodByName(context.Background(), tt.query, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findPodByName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && sf.PodObject.Name != tt.want {
+				t.Errorf("findPodByName() = %s, want %s", sf.PodObject.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindNodeByName(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	unscheduled := testPod()
+	unscheduled.Spec.NodeName = ""
+
+	tests := []struct {
+		name           string
+		pod            *v1.Pod
+		objects        []runtime.Object
+		forbidden      bool
+		wantNode       bool
+		wantNotVisible bool
+		wantErr        bool
+	}{
+		{name: "scheduled", pod: testPod(), objects: []runtime.Object{node}, wantNode: true},
+		{name: "not scheduled", pod: unscheduled},
+		{name: "node deleted", pod: testPod(), wantErr: true},
+		{name: "forbidden", pod: testPod(), objects: []runtime.Object{node}, forbidden: true, wantNotVisible: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, clientset := newTestPlugin(tt.objects...)
+			if tt.forbidden {
+				forbid(clientset, "get", "nodes")
+			}
+			sf.PodObject = tt.pod
+			err := sf.findNodeByName(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findNodeByName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := sf.AllInfo.Node != nil; got != tt.wantNode {
+				t.Errorf("findNodeByName() node found = %v, want %v", got, tt.wantNode)
+			}
+			if got := len(sf.AllInfo.NotVisible) > 0; got != tt.wantNotVisible {
+				t.Errorf("findNodeByName() not visible = %v, want %v", sf.AllInfo.NotVisible, tt.wantNotVisible)
+			}
+		})
+	}
+}
+
+func TestGetOwnerByPod(t *testing.T) {
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7d4b9", Namespace: testNamespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web"}}},
+		Status: appsv1.ReplicaSetStatus{Replicas: 2, ReadyReplicas: 1},
+	}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: objectMeta("db", nil),
+		Status:     appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: objectMeta("agent", nil),
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 4, NumberReady: 4},
+	}
+	owned := func(kind, name string) *v1.Pod {
+		pod := testPod()
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: name}}
+		return pod
+	}
+
+	tests := []struct {
+		name      string
+		pod       *v1.Pod
+		forbidden string
+		want      Workload
+		wantErr   bool
+	}{
+		{name: "deployment through replicaset", pod: testPod(),
+			want: Workload{Type: "Deployment", Name: "web", Replicas: "1/2", Status: true}},
+		{name: "statefulset", pod: owned("StatefulSet", "db"),
+			want: Workload{Type: "statefulset", Name: "db", Replicas: "3/3"}},
+		{name: "daemonset", pod: owned("DaemonSet", "agent"),
+			want: Workload{Type: "daemonset", Name: "agent", Replicas: "4/4"}},
+		{name: "other owner", pod: owned("Job", "migrate"),
+			want: Workload{Type: "job", Name: "migrate", Replicas: "0"}},
+		{name: "no owner", pod: &v1.Pod{ObjectMeta: objectMeta("standalone", nil)}},
+		{name: "missing replicaset", pod: owned("ReplicaSet", "gone"), wantErr: true},
+		{name: "replicasets forbidden", pod: testPod(), forbidden: "replicasets",
+			want: Workload{Type: "replicaset", Name: "web-7d4b9", Replicas: "?"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, clientset := newTestPlugin(rs, sts, ds)
+			if tt.forbidden != "" {
+				forbid(clientset, "get", tt.forbidden)
+			}
+			sf.PodObject = tt.pod
+			err := sf.getOwnerByPod(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getOwnerByPod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if sf.AllInfo.Workload != tt.want {
+				t.Errorf("getOwnerByPod() = %+v, want %+v", sf.AllInfo.Workload, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindByLabel(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	other := map[string]string{"app": "db"}
+	objects := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", web)},
+		&appsv1.Deployment{ObjectMeta: objectMeta("db", other)},
+		&appsv1.StatefulSet{ObjectMeta: objectMeta("web-cache", web)},
+		&appsv1.DaemonSet{ObjectMeta: objectMeta("web-agent", web)},
+		&appsv1.DaemonSet{ObjectMeta: objectMeta("db-agent", other)},
+		&v1.Service{ObjectMeta: objectMeta("web", web)},
+		&v1.Service{ObjectMeta: objectMeta("db", other)},
+		&v1.PersistentVolumeClaim{ObjectMeta: objectMeta("web-data", web)},
+		&v1.ConfigMap{ObjectMeta: objectMeta("web-config", web)},
+		&v1.ConfigMap{ObjectMeta: objectMeta("db-config", other)},
+		&v1.Secret{ObjectMeta: objectMeta("web-tls", web)},
+	}
+
+	tests := []struct {
+		name  string
+		find  func(*SnifferPlugin, context.Context, string) error
+		names func(*SnifferPlugin) []string
+		want  []string
+	}{
+		{
+			name: "deployments",
+			find: (*SnifferPlugin).findDeployByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.DeployList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web"},
+		},
+		{
+			name: "statefulsets",
+			find: (*SnifferPlugin).findStsByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.StsList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web-cache"},
+		},
+		{
+			name: "daemonsets",
+			find: (*SnifferPlugin).findDsByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.DsList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web-agent"},
+		},
+		{
+			name: "services",
+			find: (*SnifferPlugin).findSvcByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.SvcList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web"},
+		},
+		{
+			name: "persistentvolumeclaims",
+			find: (*SnifferPlugin).findPVCByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.PvcList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web-data"},
+		},
+		{
+			name: "configmaps",
+			find: (*SnifferPlugin).findConfigMapByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.ConfigMapList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web-config"},
+		},
+		{
+			name: "secrets",
+			find: (*SnifferPlugin).findSecretByLabel,
+			names: func(sf *SnifferPlugin) (names []string) {
+				for _, i := range sf.AllInfo.SecretList.Items {
+					names = append(names, i.Name)
+				}
+				return
+			},
+			want: []string{"web-tls"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(objects...)
+			sf.PodObject = testPod()
+			sf.LabelSelector = "app=web"
+			if err := tt.find(sf, context.Background(), testNamespace); err != nil {
+				t.Fatalf("find %s: %v", tt.name, err)
+			}
+			if got := tt.names(sf); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("find %s = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindByLabelForbidden(t *testing.T) {
+	sf, clientset := newTestPlugin()
+	forbid(clientset, "list", "secrets")
+	sf.PodObject = testPod()
+	sf.LabelSelector = "app=web"
+
+	err := runConcurrently(context.Background(),
+		sf.partial("secrets", func(ctx context.Context) error { return sf.findSecretByLabel(ctx, testNamespace) }),
+		sf.partial("configmaps", func(ctx context.Context) error { return sf.findConfigMapByLabel(ctx, testNamespace) }),
+	)
+	if err != nil {
+		t.Fatalf("forbidden list should not fail the lookup: %v", err)
+	}
+	want := []NotVisible{{Resource: "secrets", Reason: "forbidden"}}
+	if !reflect.DeepEqual(sf.AllInfo.NotVisible, want) {
+		t.Errorf("NotVisible = %v, want %v", sf.AllInfo.NotVisible, want)
+	}
+	if sf.AllInfo.ConfigMapList == nil {
+		t.Errorf("configmaps should still be listed")
+	}
+}
+
+func TestFindSvcBySelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector map[string]string
+		want     bool
+	}{
+		{name: "exact selector", selector: map[string]string{"app": "web", "tier": "frontend"}, want: true},
+		{name: "subset selector", selector: map[string]string{"app": "web"}, want: true},
+		{name: "different value", selector: map[string]string{"app": "db"}},
+		{name: "extra key", selector: map[string]string{"app": "web", "version": "v2"}},
+		{name: "no selector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &v1.Service{ObjectMeta: objectMeta("svc", nil), Spec: v1.ServiceSpec{Selector: tt.selector}}
+			sf, _ := newTestPlugin(svc)
+			sf.PodObject = testPod()
+			if err := sf.findSvcBySelector(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(sf.AllInfo.SvcList.Items) == 1; got != tt.want {
+				t.Errorf("findSvcBySelector() matched = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindIngressByBackend(t *testing.T) {
+	backend := func(svc string) netv1.IngressBackend {
+		return netv1.IngressBackend{Service: &netv1.IngressServiceBackend{Name: svc}}
+	}
+	rule := func(svc string) netv1.IngressRule {
+		return netv1.IngressRule{Host: "example.com", IngressRuleValue: netv1.IngressRuleValue{
+			HTTP: &netv1.HTTPIngressRuleValue{Paths: []netv1.HTTPIngressPath{{Path: "/", Backend: backend(svc)}}}}}
+	}
+	defaultBackend := backend("web")
+	objects := []runtime.Object{
+		&netv1.Ingress{ObjectMeta: objectMeta("by-rule", nil), Spec: netv1.IngressSpec{Rules: []netv1.IngressRule{rule("web")}}},
+		&netv1.Ingress{ObjectMeta: objectMeta("by-default", nil), Spec: netv1.IngressSpec{DefaultBackend: &defaultBackend}},
+		&netv1.Ingress{ObjectMeta: objectMeta("other", nil), Spec: netv1.IngressSpec{Rules: []netv1.IngressRule{rule("db")}}},
+		&netv1.Ingress{ObjectMeta: objectMeta("no-http", nil), Spec: netv1.IngressSpec{Rules: []netv1.IngressRule{{Host: "tcp"}}}},
+	}
+
+	tests := []struct {
+		name     string
+		services []string
+		want     []string
+	}{
+		{name: "matching service", services: []string{"web"}, want: []string{"by-default", "by-rule"}},
+		{name: "other service", services: []string{"db"}, want: []string{"other"}},
+		{name: "no services"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(objects...)
+			sf.PodObject = testPod()
+			sf.AllInfo.SvcList = &v1.ServiceList{}
+			for _, name := range tt.services {
+				sf.AllInfo.SvcList.Items = append(sf.AllInfo.SvcList.Items, v1.Service{ObjectMeta: objectMeta(name, nil)})
+			}
+			if err := sf.findIngressByBackend(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ing := range sf.AllInfo.IngList.Items {
+				got = append(got, ing.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findIngressByBackend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindHpaByName(t *testing.T) {
+	hpa := &autov1.HorizontalPodAutoscaler{
+		ObjectMeta: objectMeta("web", nil),
+		Spec: autov1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autov1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MaxReplicas:    5,
+		},
+	}
+	tests := []struct {
+		name     string
+		workload string
+		want     string
+	}{
+		{name: "scaled workload", workload: "web", want: "web"},
+		{name: "unscaled workload", workload: "db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(hpa)
+			sf.PodObject = testPod()
+			sf.AllInfo.Workload = Workload{Type: "Deployment", Name: tt.workload}
+			if err := sf.findHpaByName(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if sf.AllInfo.Hpa != nil {
+				got = sf.AllInfo.Hpa.Name
+			}
+			if got != tt.want {
+				t.Errorf("findHpaByName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPdbByName(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	pdb := func(name string, selector *metav1.LabelSelector) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: objectMeta(name, nil),
+			Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable, Selector: selector},
+		}
+	}
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		want     bool
+	}{
+		{name: "match labels", selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}, want: true},
+		{name: "match expressions", selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend", "backend"}}}}, want: true},
+		{name: "empty selector", selector: &metav1.LabelSelector{}, want: true},
+		{name: "other app", selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(pdb("pdb", tt.selector))
+			sf.PodObject = testPod()
+			if err := sf.findPdbByName(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(sf.AllInfo.Pdbs) == 1; got != tt.want {
+				t.Errorf("findPdbByName() matched = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLabelByPod(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    string
+		keys    []string
+		labels  map[string]string
+		want    string
+		wantErr string
+	}{
+		{name: "equality", flag: "app=web", want: "app=web"},
+		{name: "prefixed key", flag: "app.kubernetes.io/name=Web", want: "app.kubernetes.io/name=Web"},
+		{name: "inequality", flag: "tier!=backend", want: "tier!=backend"},
+		{name: "set based", flag: "env in (prod, staging),tier notin (db)", want: "env in (prod,staging),tier notin (db)"},
+		{name: "existence", flag: "app,!canary", want: "app,!canary"},
+		{name: "bad syntax", flag: "app in prod", wantErr: `invalid label selector "app in prod"`},
+		{name: "bad key", flag: "-app=web", wantErr: `invalid label selector "-app=web"`},
+		{name: "empty requirements", flag: ",", wantErr: `invalid label selector ","`},
+		{name: "fallback app", labels: map[string]string{"app": "web", "release": "r1"}, want: "app=web"},
+		{name: "fallback release", labels: map[string]string{"release": "r1"}, want: "release=r1"},
+		{name: "fallback k8s-app", labels: map[string]string{"k8s-app": "dns"}, want: "k8s-app=dns"},
+		{name: "fallback recommended label", labels: map[string]string{"app.kubernetes.io/name": "web"},
+			want: "app.kubernetes.io/name=web"},
+		{name: "no known label", labels: map[string]string{"team": "a"}},
+		{name: "flag wins over keys", flag: "app=web", keys: []string{"team"}, labels: map[string]string{"team": "a"},
+			want: "app=web"},
+		{name: "custom key order", keys: []string{"team.example.com/component", "app"},
+			labels: map[string]string{"app": "web", "team.example.com/component": "checkout"},
+			want:   "team.example.com/component=checkout"},
+		{name: "combination", keys: []string{"app.kubernetes.io/name+app.kubernetes.io/instance", "app"},
+			labels: map[string]string{"app": "web", "app.kubernetes.io/name": "web", "app.kubernetes.io/instance": "blue"},
+			want:   "app.kubernetes.io/instance=blue,app.kubernetes.io/name=web"},
+		{name: "incomplete combination", keys: []string{"app.kubernetes.io/name+app.kubernetes.io/instance", "app"},
+			labels: map[string]string{"app": "web", "app.kubernetes.io/name": "web"}, want: "app=web"},
+		{name: "invalid key", keys: []string{"app+Bad Key"}, labels: map[string]string{"app": "web"},
+			wantErr: `invalid label key "Bad Key" in "app+Bad Key"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin()
+			sf.PodObject = &v1.Pod{ObjectMeta: objectMeta("web", tt.labels)}
+			err := sf.getLabelByPod(tt.flag, tt.keys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("getLabelByPod() error = %v, want prefix %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sf.LabelSelector != tt.want {
+				t.Errorf("getLabelByPod() = %q, want %q", sf.LabelSelector, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindRelatedWithoutSelector(t *testing.T) {
+	pod := testPod()
+	pod.Labels = map[string]string{"team": "a"}
+	pod.OwnerReferences = nil
+	sf, _ := newTestPlugin(pod, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&appsv1.Deployment{ObjectMeta: objectMeta("unrelated", map[string]string{"app": "db"})})
+	sf.PodObject = pod
+	if err := sf.findRelated(context.Background(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if sf.AllInfo.DeployList != nil {
+		t.Errorf("an empty selector must not list every deployment, got %d", len(sf.AllInfo.DeployList.Items))
+	}
+}
+
+func TestFindByLabelSetBased(t *testing.T) {
+	objects := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", map[string]string{"app": "web", "env": "prod"})},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web-canary", map[string]string{"app": "web", "env": "prod", "canary": "true"})},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web-dev", map[string]string{"app": "web", "env": "dev"})},
+	}
+	sf, _ := newTestPlugin(objects...)
+	sf.PodObject = testPod()
+	if err := sf.getLabelByPod("app=web,env in (prod,staging),!canary", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.findDeployByLabel(context.Background(), testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range sf.AllInfo.DeployList.Items {
+		got = append(got, d.Name)
+	}
+	if want := []string{"web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findDeployByLabel() = %v, want %v", got, want)
+	}
+}
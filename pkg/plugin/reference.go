@@ -0,0 +1,379 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ViaVolume             = "volume"
+	ViaProjected          = "projected"
+	ViaEnv                = "env"
+	ViaEnvFrom            = "envFrom"
+	ViaImagePullSecret    = "imagePullSecret"
+	ViaServiceAccount     = "serviceAccount"
+	ViaServiceAccountPull = "serviceAccountImagePullSecret"
+)
+
+// Reference records one way a pod consumes a ConfigMap, Secret or PVC.
+type Reference struct {
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Via       string   `json:"via"`
+	Container string   `json:"container,omitempty"`
+	Volume    string   `json:"volume,omitempty"`
+	MountPath string   `json:"mountPath,omitempty"`
+	EnvVar    string   `json:"envVar,omitempty"`
+	Prefix    string   `json:"prefix,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	Optional  bool     `json:"optional,omitempty"`
+
+	ServiceAccount string   `json:"serviceAccount,omitempty"`
+	Missing        bool     `json:"missing,omitempty"`
+	MissingKeys    []string `json:"missingKeys,omitempty"`
+}
+
+// Dangling reports whether the reference points at a missing object or key
+// and is not marked optional.
+func (r Reference) Dangling() bool {
+	return !r.Optional && (r.Missing || len(r.MissingKeys) > 0)
+}
+
+var referenceStyles = map[string]string{
+	"PersistentVolumeClaim": "{{ [PVC] }}::yellow|bold %s",
+	"ConfigMap":             "{{ [ConfigMap] }}::lightMagenta|bold %s",
+	"Secret":                "{{ [Secret] }}::red|bold %s",
+}
+
+var referenceResources = map[string]string{
+	"PersistentVolumeClaim": "persistentvolumeclaims",
+	"ConfigMap":             "configmaps",
+	"Secret":                "secrets",
+}
+
+type containerRefSource struct {
+	Name    string
+	Env     []v1.EnvVar
+	EnvFrom []v1.EnvFromSource
+	Mounts  []v1.VolumeMount
+}
+
+func podContainers(pod *v1.Pod) []containerRefSource {
+	var containers []containerRefSource
+	for _, c := range pod.Spec.InitContainers {
+		containers = append(containers, containerRefSource{c.Name, c.Env, c.EnvFrom, c.VolumeMounts})
+	}
+	for _, c := range pod.Spec.Containers {
+		containers = append(containers, containerRefSource{c.Name, c.Env, c.EnvFrom, c.VolumeMounts})
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		containers = append(containers, containerRefSource{c.Name, c.Env, c.EnvFrom, c.VolumeMounts})
+	}
+	return containers
+}
+
+// podReferences collects every ConfigMap, Secret and PVC a pod spec refers to
+// through volumes, env, envFrom and imagePullSecrets.
+func podReferences(pod *v1.Pod) []Reference {
+	var refs []Reference
+	containers := podContainers(pod)
+
+	for _, vol := range pod.Spec.Volumes {
+		var volRefs []Reference
+		if vol.ConfigMap != nil {
+			volRefs = append(volRefs, Reference{Kind: "ConfigMap", Name: vol.ConfigMap.Name, Via: ViaVolume,
+				Keys: keyToPathKeys(vol.ConfigMap.Items), Optional: isOptional(vol.ConfigMap.Optional)})
+		}
+		if vol.Secret != nil {
+			volRefs = append(volRefs, Reference{Kind: "Secret", Name: vol.Secret.SecretName, Via: ViaVolume,
+				Keys: keyToPathKeys(vol.Secret.Items), Optional: isOptional(vol.Secret.Optional)})
+		}
+		if vol.PersistentVolumeClaim != nil {
+			volRefs = append(volRefs, Reference{Kind: "PersistentVolumeClaim", Name: vol.PersistentVolumeClaim.ClaimName, Via: ViaVolume})
+		}
+		if vol.Projected != nil {
+			for _, src := range vol.Projected.Sources {
+				if src.ConfigMap != nil {
+					volRefs = append(volRefs, Reference{Kind: "ConfigMap", Name: src.ConfigMap.Name, Via: ViaProjected,
+						Keys: keyToPathKeys(src.ConfigMap.Items), Optional: isOptional(src.ConfigMap.Optional)})
+				}
+				if src.Secret != nil {
+					volRefs = append(volRefs, Reference{Kind: "Secret", Name: src.Secret.Name, Via: ViaProjected,
+						Keys: keyToPathKeys(src.Secret.Items), Optional: isOptional(src.Secret.Optional)})
+				}
+			}
+		}
+		for _, ref := range volRefs {
+			ref.Volume = vol.Name
+			mounted := false
+			for _, c := range containers {
+				for _, m := range c.Mounts {
+					if m.Name != vol.Name {
+						continue
+					}
+					mounted = true
+					mref := ref
+					mref.Container = c.Name
+					mref.MountPath = m.MountPath
+					refs = append(refs, mref)
+				}
+			}
+			if !mounted {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	for _, c := range containers {
+		for _, e := range c.Env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			if r := e.ValueFrom.ConfigMapKeyRef; r != nil {
+				refs = append(refs, Reference{Kind: "ConfigMap", Name: r.Name, Via: ViaEnv, Container: c.Name,
+					EnvVar: e.Name, Keys: []string{r.Key}, Optional: isOptional(r.Optional)})
+			}
+			if r := e.ValueFrom.SecretKeyRef; r != nil {
+				refs = append(refs, Reference{Kind: "Secret", Name: r.Name, Via: ViaEnv, Container: c.Name,
+					EnvVar: e.Name, Keys: []string{r.Key}, Optional: isOptional(r.Optional)})
+			}
+		}
+		for _, e := range c.EnvFrom {
+			if r := e.ConfigMapRef; r != nil {
+				refs = append(refs, Reference{Kind: "ConfigMap", Name: r.Name, Via: ViaEnvFrom, Container: c.Name,
+					Prefix: e.Prefix, Optional: isOptional(r.Optional)})
+			}
+			if r := e.SecretRef; r != nil {
+				refs = append(refs, Reference{Kind: "Secret", Name: r.Name, Via: ViaEnvFrom, Container: c.Name,
+					Prefix: e.Prefix, Optional: isOptional(r.Optional)})
+			}
+		}
+	}
+
+	for _, s := range pod.Spec.ImagePullSecrets {
+		refs = append(refs, Reference{Kind: "Secret", Name: s.Name, Via: ViaImagePullSecret})
+	}
+	return refs
+}
+
+func serviceAccountReferences(sa *v1.ServiceAccount) []Reference {
+	var refs []Reference
+	for _, s := range sa.Secrets {
+		refs = append(refs, Reference{Kind: "Secret", Name: s.Name, Via: ViaServiceAccount, ServiceAccount: sa.Name})
+	}
+	for _, s := range sa.ImagePullSecrets {
+		refs = append(refs, Reference{Kind: "Secret", Name: s.Name, Via: ViaServiceAccountPull, ServiceAccount: sa.Name})
+	}
+	return refs
+}
+
+func (sf *SnifferPlugin) findReferences(ctx context.Context, namespace string) error {
+	sf.AllInfo.References = podReferences(sf.PodObject)
+
+	saName := sf.PodObject.Spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	sa, err := sf.Clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, saName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if sf.notVisible("serviceaccounts", err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sf.AllInfo.ServiceAccount = sa
+	sf.AllInfo.References = append(sf.AllInfo.References, serviceAccountReferences(sa)...)
+	return nil
+}
+
+// resolveReferences looks up every referenced object once and marks references
+// whose object or keys do not exist.
+func (sf *SnifferPlugin) resolveReferences(ctx context.Context, namespace string) error {
+	type object struct {
+		found   bool
+		unknown bool
+		keys    map[string]bool
+	}
+	objects := make(map[string]*object)
+	for i := range sf.AllInfo.References {
+		ref := &sf.AllInfo.References[i]
+		id := ref.Kind + "/" + ref.Name
+		obj, ok := objects[id]
+		if !ok {
+			obj = &object{keys: make(map[string]bool)}
+			var err error
+			switch ref.Kind {
+			case "ConfigMap":
+				var cm *v1.ConfigMap
+				cm, err = sf.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+				if err == nil {
+					for k := range cm.Data {
+						obj.keys[k] = true
+					}
+					for k := range cm.BinaryData {
+						obj.keys[k] = true
+					}
+				}
+			case "Secret":
+				var sec *v1.Secret
+				sec, err = sf.Clientset.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+				if err == nil {
+					for k := range sec.Data {
+						obj.keys[k] = true
+					}
+					for k := range sec.StringData {
+						obj.keys[k] = true
+					}
+				}
+			case "PersistentVolumeClaim":
+				_, err = sf.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+			}
+			if err != nil && !apierrors.IsNotFound(err) {
+				if !sf.notVisible(referenceResources[ref.Kind], err) {
+					return err
+				}
+				obj.unknown = true
+			}
+			obj.found = err == nil
+			objects[id] = obj
+		}
+		if obj.unknown {
+			continue
+		}
+		ref.Missing = !obj.found
+		if !obj.found {
+			continue
+		}
+		for _, k := range ref.Keys {
+			if !obj.keys[k] {
+				ref.MissingKeys = append(ref.MissingKeys, k)
+			}
+		}
+	}
+	return nil
+}
+
+func (sf *SnifferPlugin) danglingReferences() []Reference {
+	var dangling []Reference
+	for _, ref := range sf.AllInfo.References {
+		if ref.Dangling() {
+			dangling = append(dangling, ref)
+		}
+	}
+	return dangling
+}
+
+// Usage describes how the reference is consumed, e.g. "nginx mounted at /etc/nginx".
+func (r Reference) Usage() string {
+	switch r.Via {
+	case ViaVolume, ViaProjected:
+		if r.Container == "" {
+			return fmt.Sprintf("%s volume %s (not mounted)", r.Via, r.Volume)
+		}
+		usage := fmt.Sprintf("%s mounted at %s", r.Container, r.MountPath)
+		if len(r.Keys) > 0 {
+			usage += " keys: " + strings.Join(r.Keys, ",")
+		}
+		return usage
+	case ViaEnv:
+		return fmt.Sprintf("%s env %s ← key %s", r.Container, r.EnvVar, strings.Join(r.Keys, ","))
+	case ViaEnvFrom:
+		if r.Prefix != "" {
+			return fmt.Sprintf("%s envFrom (prefix %s)", r.Container, r.Prefix)
+		}
+		return fmt.Sprintf("%s envFrom", r.Container)
+	case ViaImagePullSecret:
+		return "imagePullSecret"
+	case ViaServiceAccount:
+		return fmt.Sprintf("serviceAccount %s secret", r.ServiceAccount)
+	case ViaServiceAccountPull:
+		return fmt.Sprintf("serviceAccount %s imagePullSecret", r.ServiceAccount)
+	}
+	return r.Via
+}
+
+// Problem describes what is wrong with the reference, or is empty when it resolves.
+func (r Reference) Problem() string {
+	var problem string
+	switch {
+	case r.Missing:
+		problem = r.Kind + " not found"
+	case len(r.MissingKeys) > 0:
+		problem = "missing key " + strings.Join(r.MissingKeys, ",")
+	default:
+		return ""
+	}
+	if r.Optional {
+		problem += " (optional)"
+	}
+	return problem
+}
+
+func (sf *SnifferPlugin) referenceLeveledList() []pterm.LeveledListItem {
+	var leveledList []pterm.LeveledListItem
+	for _, group := range groupReferences(sf.AllInfo.References) {
+		text := cfmt.Sprintf(referenceStyles[group[0].Kind], group[0].Name)
+		if group[0].Missing {
+			text += cfmt.Sprintf(" {{(missing)}}::%s|bold", problemColor(group))
+		}
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 2, Text: text})
+		for _, ref := range group {
+			usage := cfmt.Sprintf("{{%s}}::gray", ref.Usage())
+			if p := ref.Problem(); p != "" && !ref.Missing {
+				usage += cfmt.Sprintf(" {{%s}}::%s|bold", p, problemColor([]Reference{ref}))
+			}
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 3, Text: usage})
+		}
+	}
+	return leveledList
+}
+
+// problemColor is red when any of the references is dangling and yellow when
+// the problem only affects optional references.
+func problemColor(refs []Reference) string {
+	for _, r := range refs {
+		if r.Dangling() {
+			return "red"
+		}
+	}
+	return "yellow"
+}
+
+// groupReferences groups references by kind and name, keeping first-seen order.
+func groupReferences(refs []Reference) [][]Reference {
+	var groups [][]Reference
+	index := make(map[string]int)
+	for _, r := range refs {
+		key := r.Kind + "/" + r.Name
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups
+}
+
+func keyToPathKeys(items []v1.KeyToPath) []string {
+	var keys []string
+	for _, i := range items {
+		keys = append(keys, i.Key)
+	}
+	return keys
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFindReferences(t *testing.T) {
+	optional := true
+	pod := testPod()
+	pod.Spec.ServiceAccountName = "web"
+	pod.Spec.Volumes = []v1.Volume{
+		{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+			LocalObjectReference: v1.LocalObjectReference{Name: "web-config"}}}},
+		{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+			ClaimName: "web-data"}}},
+	}
+	pod.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{{Name: "config", MountPath: "/etc/web"}}
+	pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{
+		SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "web-db"}, Key: "password"}}}}
+	pod.Spec.Containers[0].EnvFrom = []v1.EnvFromSource{{Prefix: "OPT_", ConfigMapRef: &v1.ConfigMapEnvSource{
+		LocalObjectReference: v1.LocalObjectReference{Name: "web-extra"}, Optional: &optional}}}
+	pod.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: "registry"}}
+	sa := &v1.ServiceAccount{
+		ObjectMeta:       objectMeta("web", nil),
+		ImagePullSecrets: []v1.LocalObjectReference{{Name: "sa-registry"}},
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    []Reference
+	}{
+		{
+			name:    "with service account",
+			objects: []runtime.Object{sa},
+			want: []Reference{
+				{Kind: "ConfigMap", Name: "web-config", Via: ViaVolume, Volume: "config", Container: "app", MountPath: "/etc/web"},
+				{Kind: "PersistentVolumeClaim", Name: "web-data", Via: ViaVolume, Volume: "data"},
+				{Kind: "Secret", Name: "web-db", Via: ViaEnv, Container: "app", EnvVar: "PASSWORD", Keys: []string{"password"}},
+				{Kind: "ConfigMap", Name: "web-extra", Via: ViaEnvFrom, Container: "app", Prefix: "OPT_", Optional: true},
+				{Kind: "Secret", Name: "registry", Via: ViaImagePullSecret},
+				{Kind: "Secret", Name: "sa-registry", Via: ViaServiceAccountPull, ServiceAccount: "web"},
+			},
+		},
+		{
+			name: "missing service account",
+			want: []Reference{
+				{Kind: "ConfigMap", Name: "web-config", Via: ViaVolume, Volume: "config", Container: "app", MountPath: "/etc/web"},
+				{Kind: "PersistentVolumeClaim", Name: "web-data", Via: ViaVolume, Volume: "data"},
+				{Kind: "Secret", Name: "web-db", Via: ViaEnv, Container: "app", EnvVar: "PASSWORD", Keys: []string{"password"}},
+				{Kind: "ConfigMap", Name: "web-extra", Via: ViaEnvFrom, Container: "app", Prefix: "OPT_", Optional: true},
+				{Kind: "Secret", Name: "registry", Via: ViaImagePullSecret},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(tt.objects...)
+			sf.PodObject = pod
+			if err := sf.findReferences(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sf.AllInfo.References, tt.want) {
+				t.Errorf("findReferences() =\n%+v\nwant\n%+v", sf.AllInfo.References, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	objects := []runtime.Object{
+		&v1.ConfigMap{ObjectMeta: objectMeta("web-config", nil), Data: map[string]string{"nginx.conf": ""}},
+		&v1.Secret{ObjectMeta: objectMeta("web-db", nil), Data: map[string][]byte{"password": nil}},
+		&v1.PersistentVolumeClaim{ObjectMeta: objectMeta("web-data", nil)},
+	}
+
+	tests := []struct {
+		name            string
+		ref             Reference
+		forbidden       string
+		wantMissing     bool
+		wantMissingKeys []string
+		wantDangling    bool
+		wantNotVisible  bool
+	}{
+		{name: "existing configmap key", ref: Reference{Kind: "ConfigMap", Name: "web-config", Keys: []string{"nginx.conf"}}},
+		{name: "missing configmap key", ref: Reference{Kind: "ConfigMap", Name: "web-config", Keys: []string{"app.conf"}},
+			wantMissingKeys: []string{"app.conf"}, wantDangling: true},
+		{name: "missing configmap", ref: Reference{Kind: "ConfigMap", Name: "gone"},
+			wantMissing: true, wantDangling: true},
+		{name: "missing optional configmap", ref: Reference{Kind: "ConfigMap", Name: "gone", Optional: true},
+			wantMissing: true},
+		{name: "existing secret key", ref: Reference{Kind: "Secret", Name: "web-db", Keys: []string{"password"}}},
+		{name: "existing pvc", ref: Reference{Kind: "PersistentVolumeClaim", Name: "web-data"}},
+		{name: "missing pvc", ref: Reference{Kind: "PersistentVolumeClaim", Name: "gone"},
+			wantMissing: true, wantDangling: true},
+		{name: "forbidden secret", ref: Reference{Kind: "Secret", Name: "gone", Keys: []string{"password"}},
+			forbidden: "secrets", wantNotVisible: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, clientset := newTestPlugin(objects...)
+			if tt.forbidden != "" {
+				forbid(clientset, "get", tt.forbidden)
+			}
+			sf.PodObject = testPod()
+			sf.AllInfo.References = []Reference{tt.ref}
+			if err := sf.resolveReferences(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			got := sf.AllInfo.References[0]
+			if got.Missing != tt.wantMissing {
+				t.Errorf("Missing = %v, want %v", got.Missing, tt.wantMissing)
+			}
+			if !reflect.DeepEqual(got.MissingKeys, tt.wantMissingKeys) {
+				t.Errorf("MissingKeys = %v, want %v", got.MissingKeys, tt.wantMissingKeys)
+			}
+			if got.Dangling() != tt.wantDangling {
+				t.Errorf("Dangling() = %v, want %v", got.Dangling(), tt.wantDangling)
+			}
+			if visible := len(sf.AllInfo.NotVisible) > 0; visible != tt.wantNotVisible {
+				t.Errorf("NotVisible = %v, want %v", sf.AllInfo.NotVisible, tt.wantNotVisible)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"strings"
+
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type ObjectRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type Relationship struct {
+	From ObjectRef `json:"from"`
+	To   ObjectRef `json:"to"`
+	Type string    `json:"type"`
+}
+
+var workloadKinds = map[string]string{
+	"deployment":  "Deployment",
+	"statefulset": "StatefulSet",
+	"daemonset":   "DaemonSet",
+	"replicaset":  "ReplicaSet",
+	"job":         "Job",
+	"cronjob":     "CronJob",
+}
+
+var referenceRelations = map[string]string{
+	ViaVolume:             "mounts",
+	ViaProjected:          "mounts",
+	ViaEnv:                "env",
+	ViaEnvFrom:            "envFrom",
+	ViaImagePullSecret:    "pulls-with",
+	ViaServiceAccount:     "references",
+	ViaServiceAccountPull: "pulls-with",
+}
+
+func newObjectRef(kind string, meta metav1.Object) ObjectRef {
+	return ObjectRef{Kind: kind, Namespace: meta.GetNamespace(), Name: meta.GetName()}
+}
+
+func (sf *SnifferPlugin) workloadRef() ObjectRef {
+	kind := sf.AllInfo.Workload.Type
+	if k, ok := workloadKinds[strings.ToLower(kind)]; ok {
+		kind = k
+	}
+	return ObjectRef{Kind: kind, Namespace: sf.PodObject.Namespace, Name: sf.AllInfo.Workload.Name}
+}
+
+func (sf *SnifferPlugin) buildRelationships() []Relationship {
+	var rels []Relationship
+	pod := newObjectRef("Pod", sf.PodObject)
+	add := func(from, to ObjectRef, relType string) {
+		rels = append(rels, Relationship{From: from, To: to, Type: relType})
+	}
+
+	if sf.fromWorkload {
+		// The shared resources hang off the workload, which manages the pods.
+		pod = sf.workloadRef()
+		add(ObjectRef{Kind: "Namespace", Name: sf.PodObject.Namespace}, pod, "contains")
+		for i := range sf.AllInfo.Pods {
+			p := newObjectRef("Pod", &sf.AllInfo.Pods[i])
+			add(pod, p, "manages")
+			if node := sf.AllInfo.Pods[i].Spec.NodeName; node != "" {
+				add(p, ObjectRef{Kind: "Node", Name: node}, "scheduled-on")
+			}
+		}
+	} else {
+		add(ObjectRef{Kind: "Namespace", Name: sf.PodObject.Namespace}, pod, "contains")
+		if sf.AllInfo.Workload.Name != "" {
+			add(sf.workloadRef(), pod, "manages")
+		}
+		if sf.PodObject.Spec.NodeName != "" {
+			add(pod, ObjectRef{Kind: "Node", Name: sf.PodObject.Spec.NodeName}, "scheduled-on")
+		}
+	}
+	if sa := sf.AllInfo.ServiceAccount; sa != nil {
+		add(pod, newObjectRef("ServiceAccount", sa), "runs-as")
+	}
+
+	services := make(map[string]bool)
+	if sf.AllInfo.SvcList != nil {
+		for _, svc := range sf.AllInfo.SvcList.Items {
+			services[svc.Name] = true
+			relType := "shares-label"
+			if len(svc.Spec.Selector) > 0 &&
+				labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(sf.PodObject.Labels)) {
+				relType = "selects"
+			}
+			add(newObjectRef("Service", &svc), pod, relType)
+		}
+	}
+	if sf.AllInfo.IngList != nil {
+		for _, ing := range sf.AllInfo.IngList.Items {
+			ingRef := newObjectRef("Ingress", &ing)
+			for _, name := range ingressBackends(ing) {
+				if services[name] {
+					add(ingRef, ObjectRef{Kind: "Service", Namespace: ing.Namespace, Name: name}, "routes-to")
+				}
+			}
+		}
+	}
+
+	for _, route := range sf.AllInfo.Routes {
+		routeRef := ObjectRef{Kind: route.Kind, Namespace: route.Namespace, Name: route.Name}
+		for _, p := range route.Parents {
+			add(ObjectRef{Kind: p.Kind, Namespace: p.Namespace, Name: p.Name}, routeRef, "parent-of")
+		}
+		for _, name := range route.Backends {
+			if services[name] {
+				add(routeRef, ObjectRef{Kind: "Service", Namespace: route.Namespace, Name: name}, "routes-to")
+			}
+		}
+	}
+
+	seenRefs := make(map[Relationship]bool)
+	for _, ref := range sf.AllInfo.References {
+		from, relType := pod, referenceRelations[ref.Via]
+		if ref.ServiceAccount != "" {
+			from = ObjectRef{Kind: "ServiceAccount", Namespace: sf.PodObject.Namespace, Name: ref.ServiceAccount}
+		}
+		rel := Relationship{From: from, To: ObjectRef{Kind: ref.Kind, Namespace: sf.PodObject.Namespace, Name: ref.Name}, Type: relType}
+		if !seenRefs[rel] {
+			seenRefs[rel] = true
+			rels = append(rels, rel)
+		}
+	}
+	if sf.AllInfo.PvcList != nil {
+		for _, pvc := range sf.AllInfo.PvcList.Items {
+			if pvc.Spec.VolumeName != "" {
+				add(newObjectRef("PersistentVolumeClaim", &pvc),
+					ObjectRef{Kind: "PersistentVolume", Name: pvc.Spec.VolumeName}, "bound-to")
+			}
+		}
+	}
+
+	if sf.AllInfo.Hpa != nil && sf.AllInfo.Workload.Name != "" {
+		add(newObjectRef("HorizontalPodAutoscaler", sf.AllInfo.Hpa), sf.workloadRef(), "scales")
+	}
+	for _, pdb := range sf.AllInfo.Pdbs {
+		add(newObjectRef("PodDisruptionBudget", pdb), pod, "protects")
+	}
+	return rels
+}
+
+// ingressBackends returns the distinct service names an ingress routes to, in spec order.
+func ingressBackends(ing netv1.Ingress) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(backend netv1.IngressBackend) {
+		if backend.Service != nil && !seen[backend.Service.Name] {
+			seen[backend.Service.Name] = true
+			names = append(names, backend.Service.Name)
+		}
+	}
+	if ing.Spec.DefaultBackend != nil {
+		add(*ing.Spec.DefaultBackend)
+	}
+	for _, r := range ing.Spec.Rules {
+		if r.HTTP == nil {
+			continue
+		}
+		for _, p := range r.HTTP.Paths {
+			add(p.Backend)
+		}
+	}
+	return names
+}
@@ -0,0 +1,529 @@
+package plugin
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	svgBoxWidth   = 190
+	svgBoxHeight  = 44
+	svgColumnGap  = 90
+	svgRowGap     = 22
+	svgMargin     = 20
+	svgLabelChars = 26
+)
+
+// kindColumns places each kind in a column of the report graph, traffic on the
+// left, the pod in the middle and its dependencies on the right.
+var kindColumns = map[string]int{
+	"Gateway":                 0,
+	"Ingress":                 0,
+	"HTTPRoute":               0,
+	"GRPCRoute":               0,
+	"HorizontalPodAutoscaler": 0,
+	"Namespace":               0,
+	"Service":                 1,
+	"PodDisruptionBudget":     1,
+	"Deployment":              1,
+	"StatefulSet":             1,
+	"DaemonSet":               1,
+	"ReplicaSet":              1,
+	"Job":                     1,
+	"CronJob":                 1,
+	"Pod":                     2,
+	"ServiceAccount":          3,
+	"Node":                    3,
+	"ConfigMap":               3,
+	"Secret":                  3,
+	"PersistentVolumeClaim":   3,
+	"PersistentVolume":        4,
+}
+
+type reportSection struct {
+	Kind string
+	Name string
+	Rows [][2]string
+}
+
+type reportContainer struct {
+	Name     string
+	Type     string
+	Image    string
+	State    string
+	Ready    bool
+	Restarts int32
+	Last     *ContainerDiagnosis
+}
+
+type reportData struct {
+	Title      string
+	Generated  string
+	Workload   Workload
+	Selector   string
+	Node       string
+	Graph      template.HTML
+	Containers []reportContainer
+	Sections   []reportSection
+	Events     []TimelineEvent
+	Relations  []Relationship
+	References []Reference
+	Scheduling *SchedulingAnalysis
+	NotVisible []NotVisible
+	Unhealthy  bool
+	PodPhase   string
+	PodIP      string
+	NodeIP     string
+	APIVersion string
+}
+
+func (sf *SnifferPlugin) writeReportFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create report file")
+	}
+	if err := sf.writeReport(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return errors.Wrap(f.Close(), "failed to write report file")
+}
+
+func (sf *SnifferPlugin) writeReport(w io.Writer) error {
+	rels := sf.buildRelationships()
+	data := reportData{
+		Title:      fmt.Sprintf("%s/%s", sf.PodObject.Namespace, sf.PodObject.Name),
+		Generated:  time.Now().Format(time.RFC3339),
+		Workload:   sf.AllInfo.Workload,
+		Selector:   sf.LabelSelector,
+		Node:       sf.PodObject.Spec.NodeName,
+		Graph:      renderGraphSVG(newGraph(rels)),
+		Containers: sf.reportContainers(),
+		Sections:   sf.reportSections(),
+		Events:     sf.AllInfo.Events,
+		Relations:  rels,
+		References: sf.AllInfo.References,
+		Scheduling: sf.AllInfo.Scheduling,
+		NotVisible: sf.AllInfo.NotVisible,
+		PodPhase:   string(sf.PodObject.Status.Phase),
+		PodIP:      sf.PodObject.Status.PodIP,
+		APIVersion: documentAPIVersion,
+	}
+	if sf.AllInfo.Node != nil {
+		for _, ip := range sf.AllInfo.Node.Status.Addresses {
+			if ip.Type == v1.NodeInternalIP {
+				data.NodeIP = ip.Address
+			}
+		}
+	}
+	data.Unhealthy = sf.PodObject.Status.Phase != v1.PodRunning && sf.PodObject.Status.Phase != v1.PodSucceeded
+	if sf.fromWorkload {
+		summaries := sf.podSummaries()
+		data.PodPhase = podStateCounts(summaries)
+		data.Unhealthy = sf.AllInfo.Workload.Status
+		for _, s := range summaries {
+			data.Unhealthy = data.Unhealthy || s.Unhealthy
+		}
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+func (sf *SnifferPlugin) reportContainers() []reportContainer {
+	var containers []reportContainer
+	images := make(map[string]string)
+	for _, c := range sf.PodObject.Spec.InitContainers {
+		images[c.Name] = c.Image
+	}
+	for _, c := range sf.PodObject.Spec.Containers {
+		images[c.Name] = c.Image
+	}
+	add := func(kind string, statuses []v1.ContainerStatus) {
+		for _, val := range statuses {
+			containers = append(containers, reportContainer{
+				Name:     val.Name,
+				Type:     kind,
+				Image:    images[val.Name],
+				State:    containerState(val.State),
+				Ready:    val.Ready,
+				Restarts: val.RestartCount,
+				Last:     sf.diagnosis(val.Name),
+			})
+		}
+	}
+	add("initContainer", sf.PodObject.Status.InitContainerStatuses)
+	add("container", sf.PodObject.Status.ContainerStatuses)
+	return containers
+}
+
+func containerState(state v1.ContainerState) string {
+	switch {
+	case state.Terminated != nil:
+		return state.Terminated.Reason
+	case state.Waiting != nil:
+		return state.Waiting.Reason
+	case state.Running != nil:
+		return "Running"
+	}
+	return "Unknown"
+}
+
+func (sf *SnifferPlugin) reportSections() []reportSection {
+	var sections []reportSection
+	if sf.fromWorkload {
+		for _, s := range sf.podSummaries() {
+			sections = append(sections, reportSection{Kind: "Pod", Name: s.Name, Rows: [][2]string{
+				{"State", s.State},
+				{"Ready", s.Ready},
+				{"Restarts", fmt.Sprint(s.Restarts)},
+				{"Node", s.Node},
+				{"Pod IP", s.PodIP},
+			}})
+		}
+	}
+	if l := sf.AllInfo.DeployList; l != nil {
+		for _, i := range l.Items {
+			sections = append(sections, reportSection{Kind: "Deployment", Name: i.Name, Rows: [][2]string{
+				{"Replicas", fmt.Sprintf("%d/%d", i.Status.ReadyReplicas, i.Status.Replicas)},
+			}})
+		}
+	}
+	if l := sf.AllInfo.StsList; l != nil {
+		for _, i := range l.Items {
+			sections = append(sections, reportSection{Kind: "StatefulSet", Name: i.Name, Rows: [][2]string{
+				{"Replicas", fmt.Sprintf("%d/%d", i.Status.ReadyReplicas, i.Status.Replicas)},
+			}})
+		}
+	}
+	if l := sf.AllInfo.DsList; l != nil {
+		for _, i := range l.Items {
+			sections = append(sections, reportSection{Kind: "DaemonSet", Name: i.Name, Rows: [][2]string{
+				{"Replicas", fmt.Sprintf("%d/%d", i.Status.NumberReady, i.Status.DesiredNumberScheduled)},
+			}})
+		}
+	}
+	if l := sf.AllInfo.SvcList; l != nil {
+		for _, i := range l.Items {
+			s := reportSection{Kind: "Service", Name: i.Name}
+			s.Rows = append(s.Rows, [2]string{"Type", string(i.Spec.Type)})
+			if len(i.Spec.Selector) > 0 {
+				s.Rows = append(s.Rows, [2]string{"Selector", labels.SelectorFromSet(i.Spec.Selector).String()})
+			}
+			if i.Spec.ClusterIP != "None" {
+				s.Rows = append(s.Rows, [2]string{"Cluster IP", i.Spec.ClusterIP})
+			}
+			for _, p := range i.Spec.Ports {
+				s.Rows = append(s.Rows, [2]string{"Port " + p.Name,
+					fmt.Sprintf("%d → %s/%s", p.Port, p.TargetPort.String(), p.Protocol)})
+			}
+			for _, ing := range i.Status.LoadBalancer.Ingress {
+				if ing.IP != "" {
+					s.Rows = append(s.Rows, [2]string{"IP", ing.IP})
+				}
+				if ing.Hostname != "" {
+					s.Rows = append(s.Rows, [2]string{"Host", ing.Hostname})
+				}
+			}
+			sections = append(sections, s)
+		}
+	}
+	if l := sf.AllInfo.IngList; l != nil {
+		for _, i := range l.Items {
+			s := reportSection{Kind: "Ingress", Name: i.Name}
+			for _, r := range i.Spec.Rules {
+				if r.HTTP == nil {
+					continue
+				}
+				for _, p := range r.HTTP.Paths {
+					backend := ""
+					if p.Backend.Service != nil {
+						backend = p.Backend.Service.Name
+					}
+					s.Rows = append(s.Rows, [2]string{"https://" + r.Host + p.Path, backend})
+				}
+			}
+			for _, lb := range i.Status.LoadBalancer.Ingress {
+				if lb.IP != "" {
+					s.Rows = append(s.Rows, [2]string{"LoadBalance IP", lb.IP})
+				}
+				if lb.Hostname != "" {
+					s.Rows = append(s.Rows, [2]string{"LoadBalance Host", lb.Hostname})
+				}
+			}
+			sections = append(sections, s)
+		}
+	}
+	for _, route := range sf.AllInfo.Routes {
+		s := reportSection{Kind: route.Kind, Name: route.Name}
+		for _, h := range route.Hostnames {
+			s.Rows = append(s.Rows, [2]string{"Host", h})
+		}
+		for _, p := range route.Parents {
+			s.Rows = append(s.Rows, [2]string{"Parent", fmt.Sprintf("%s %s/%s", p.Kind, p.Namespace, p.Name)})
+		}
+		s.Rows = append(s.Rows, [2]string{"Backends", strings.Join(route.Backends, ", ")})
+		sections = append(sections, s)
+	}
+	if l := sf.AllInfo.PvcList; l != nil {
+		for _, i := range l.Items {
+			s := reportSection{Kind: "PersistentVolumeClaim", Name: i.Name}
+			if i.Spec.StorageClassName != nil {
+				s.Rows = append(s.Rows, [2]string{"Storage Class", *i.Spec.StorageClassName})
+			}
+			var modes []string
+			for _, m := range i.Spec.AccessModes {
+				modes = append(modes, string(m))
+			}
+			size := i.Spec.Resources.Requests[v1.ResourceStorage]
+			s.Rows = append(s.Rows,
+				[2]string{"Access Modes", strings.Join(modes, ", ")},
+				[2]string{"Size", size.String()},
+				[2]string{"Phase", string(i.Status.Phase)},
+				[2]string{"PV Name", i.Spec.VolumeName})
+			sections = append(sections, s)
+		}
+	}
+	if l := sf.AllInfo.ConfigMapList; l != nil {
+		for _, i := range l.Items {
+			keys := make([]string, 0, len(i.Data))
+			for k := range i.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			sections = append(sections, reportSection{Kind: "ConfigMap", Name: i.Name, Rows: [][2]string{
+				{"Keys", strings.Join(keys, ", ")},
+			}})
+		}
+	}
+	if l := sf.AllInfo.SecretList; l != nil {
+		for _, i := range l.Items {
+			sum := summarizeSecret(i)
+			sections = append(sections, reportSection{Kind: "Secret", Name: i.Name, Rows: [][2]string{
+				{"Type", string(sum.Type)},
+				{"Keys", strings.Join(sum.Keys, ", ")},
+			}})
+		}
+	}
+	if hpa := sf.AllInfo.Hpa; hpa != nil {
+		s := reportSection{Kind: "HorizontalPodAutoscaler", Name: hpa.Name}
+		if hpa.Spec.MinReplicas != nil {
+			s.Rows = append(s.Rows, [2]string{"Min", fmt.Sprint(*hpa.Spec.MinReplicas)})
+		}
+		s.Rows = append(s.Rows,
+			[2]string{"Max", fmt.Sprint(hpa.Spec.MaxReplicas)},
+			[2]string{"Current", fmt.Sprint(hpa.Status.CurrentReplicas)})
+		sections = append(sections, s)
+	}
+	for _, pdb := range sf.AllInfo.Pdbs {
+		s := reportSection{Kind: "PodDisruptionBudget", Name: pdb.Name}
+		if pdb.Spec.MinAvailable != nil {
+			s.Rows = append(s.Rows, [2]string{"MinAvailable", pdb.Spec.MinAvailable.String()})
+		}
+		if pdb.Spec.MaxUnavailable != nil {
+			s.Rows = append(s.Rows, [2]string{"MaxUnavailable", pdb.Spec.MaxUnavailable.String()})
+		}
+		s.Rows = append(s.Rows, [2]string{"Disruptions", fmt.Sprint(pdb.Status.DisruptionsAllowed)})
+		sections = append(sections, s)
+	}
+	return sections
+}
+
+// renderGraphSVG lays the graph out in columns by kind and draws it as inline SVG,
+// so the report does not depend on any external renderer.
+func renderGraphSVG(g *Graph) template.HTML {
+	type point struct{ x, y int }
+	columns := make(map[int]int)
+	pos := make(map[ObjectRef]point, len(g.Nodes))
+	maxCol, maxRow := 0, 0
+	for _, n := range g.Nodes {
+		col, ok := kindColumns[n.Kind]
+		if !ok {
+			col = 3
+		}
+		row := columns[col]
+		columns[col]++
+		pos[n] = point{
+			x: svgMargin + col*(svgBoxWidth+svgColumnGap),
+			y: svgMargin + row*(svgBoxHeight+svgRowGap),
+		}
+		if col > maxCol {
+			maxCol = col
+		}
+		if row > maxRow {
+			maxRow = row
+		}
+	}
+	width := 2*svgMargin + (maxCol+1)*svgBoxWidth + maxCol*svgColumnGap
+	height := 2*svgMargin + (maxRow+1)*svgBoxHeight + maxRow*svgRowGap
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#555"/></marker></defs>`)
+	for _, e := range g.Edges {
+		from, to := pos[e.From], pos[e.To]
+		x1, x2 := from.x+svgBoxWidth, to.x
+		if from.x > to.x {
+			x1, x2 = from.x, to.x+svgBoxWidth
+		} else if from.x == to.x {
+			x1, x2 = from.x+svgBoxWidth, to.x+svgBoxWidth
+		}
+		y1, y2 := from.y+svgBoxHeight/2, to.y+svgBoxHeight/2
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#555" marker-end="url(#arrow)"/>`,
+			x1, y1, x2, y2)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="edge">%s</text>`,
+			(x1+x2)/2, (y1+y2)/2-4, html.EscapeString(e.Type))
+	}
+	for _, n := range g.Nodes {
+		p := pos[n]
+		color, ok := kindColors[n.Kind]
+		if !ok {
+			color = "#eeeeee"
+		}
+		name := n.Name
+		if len(name) > svgLabelChars {
+			name = name[:svgLabelChars-1] + "…"
+		}
+		fmt.Fprintf(&b, `<g><title>%s</title><rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="#555"/>`,
+			html.EscapeString(n.String()), p.x, p.y, svgBoxWidth, svgBoxHeight, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="kind">%s</text><text x="%d" y="%d" class="name">%s</text></g>`,
+			p.x+8, p.y+17, html.EscapeString(n.Kind), p.x+8, p.y+35, html.EscapeString(name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>pod-lens: {{ .Title }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h1 small { color: #777; font-weight: normal; font-size: 0.6em; }
+details { border: 1px solid #ddd; border-radius: 6px; margin: 1em 0; padding: 0.5em 1em; }
+summary { font-weight: bold; cursor: pointer; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border-bottom: 1px solid #eee; padding: 4px 10px; text-align: left; vertical-align: top; font-size: 0.9em; }
+th { background: #f5f5f5; }
+.bad { color: #c62828; font-weight: bold; }
+.good { color: #2e7d32; font-weight: bold; }
+.warn { color: #ef6c00; }
+.graph { overflow-x: auto; }
+svg text.kind { font-size: 11px; font-weight: bold; fill: #333; }
+svg text.name { font-size: 12px; fill: #111; }
+svg text.edge { font-size: 10px; fill: #555; text-anchor: middle; }
+footer { color: #999; font-size: 0.8em; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>Pod {{ .Title }} <small>generated {{ .Generated }}</small></h1>
+<table>
+<tr><th>Phase</th><td class="{{ if .Unhealthy }}bad{{ else }}good{{ end }}">{{ .PodPhase }}</td></tr>
+<tr><th>Pod IP</th><td>{{ .PodIP }}</td></tr>
+<tr><th>Workload</th><td>{{ .Workload.Type }} {{ .Workload.Name }} <span class="{{ if .Workload.Status }}bad{{ else }}good{{ end }}">{{ .Workload.Replicas }}</span></td></tr>
+<tr><th>Node</th><td>{{ .Node }} {{ .NodeIP }}</td></tr>
+{{- with .Selector }}
+<tr><th>Label selector</th><td>{{ . }}</td></tr>
+{{- end }}
+</table>
+
+{{- with .NotVisible }}
+<details open>
+<summary>Not visible</summary>
+<table>
+<tr><th>Resource</th><th>Reason</th></tr>
+{{- range . }}
+<tr><td>{{ .Resource }}</td><td class="warn">{{ .Reason }}</td></tr>
+{{- end }}
+</table>
+</details>
+{{- end }}
+
+{{- with .Scheduling }}
+<details open>
+<summary>Scheduling analysis</summary>
+<ul>
+{{- range .Issues }}
+<li class="bad">{{ . }}</li>
+{{- end }}
+</ul>
+<table>
+<tr><th>Node</th><th>Reasons</th></tr>
+{{- range .Nodes }}
+<tr><td>{{ .Node }}</td><td>{{ if .Reasons }}{{ range $i, $r := .Reasons }}{{ if $i }}, {{ end }}{{ $r }}{{ end }}{{ else }}<span class="good">fits</span>{{ end }}</td></tr>
+{{- end }}
+</table>
+</details>
+{{- end }}
+
+<details open>
+<summary>Relationship graph</summary>
+<div class="graph">{{ .Graph }}</div>
+<table>
+<tr><th>From</th><th>Relationship</th><th>To</th></tr>
+{{- range .Relations }}
+<tr><td>{{ .From.Kind }}/{{ .From.Name }}</td><td>{{ .Type }}</td><td>{{ .To.Kind }}/{{ .To.Name }}</td></tr>
+{{- end }}
+</table>
+</details>
+
+<details open>
+<summary>Containers ({{ len .Containers }})</summary>
+<table>
+<tr><th>Name</th><th>Type</th><th>Image</th><th>State</th><th>Ready</th><th>Restarts</th><th>Last termination</th></tr>
+{{- range .Containers }}
+<tr><td>{{ .Name }}</td><td>{{ .Type }}</td><td>{{ .Image }}</td><td>{{ .State }}</td><td class="{{ if .Ready }}good{{ else }}bad{{ end }}">{{ .Ready }}</td><td{{ if .Restarts }} class="bad"{{ end }}>{{ .Restarts }}</td>
+<td>{{ with .Last }}<span class="bad">{{ .Reason }} exit {{ .ExitCode }}{{ if .SignalName }} {{ .SignalName }}{{ end }}</span>{{ if .Meaning }}<br>{{ .Meaning }}{{ end }}{{ if .Message }}<pre>{{ .Message }}</pre>{{ end }}{{ range .Hints }}<br><em>{{ . }}</em>{{ end }}{{ end }}</td></tr>
+{{- end }}
+</table>
+</details>
+
+<details open>
+<summary>References ({{ len .References }})</summary>
+<table>
+<tr><th>Kind</th><th>Name</th><th>Via</th><th>Usage</th><th>Problem</th></tr>
+{{- range .References }}
+<tr><td>{{ .Kind }}</td><td{{ if .Dangling }} class="bad"{{ end }}>{{ .Name }}</td><td>{{ .Via }}</td><td>{{ .Usage }}</td><td{{ if .Dangling }} class="bad"{{ end }}>{{ .Problem }}</td></tr>
+{{- end }}
+</table>
+</details>
+
+<details open>
+<summary>Related resources ({{ len .Sections }})</summary>
+{{- range .Sections }}
+<details>
+<summary>{{ .Kind }} {{ .Name }}</summary>
+<table>
+{{- range .Rows }}
+<tr><th>{{ index . 0 }}</th><td>{{ index . 1 }}</td></tr>
+{{- end }}
+</table>
+</details>
+{{- end }}
+</details>
+
+<details open>
+<summary>Events ({{ len .Events }})</summary>
+<table>
+<tr><th>Last seen</th><th>First seen</th><th>Count</th><th>Type</th><th>Object</th><th>Reason</th><th>Message</th></tr>
+{{- range .Events }}
+<tr><td>{{ .LastSeen.Format "2006-01-02 15:04:05" }}</td><td>{{ .FirstSeen.Format "2006-01-02 15:04:05" }}</td><td>{{ .Count }}</td><td{{ if ne .Type "Normal" }} class="bad"{{ end }}>{{ .Type }}</td><td>{{ .Object.Kind }}/{{ .Object.Name }}</td><td>{{ .Reason }}</td><td>{{ .Message }}</td></tr>
+{{- end }}
+</table>
+</details>
+
+<footer>Generated by kubectl pod-lens ({{ .APIVersion }}).</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestWriteReport(t *testing.T) {
+	objects := append(testCluster(), &v1.Event{
+		ObjectMeta:     objectMeta("web.1", nil),
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: testNamespace, Name: "web-7d4b9-abcde"},
+		Type:           v1.EventTypeWarning, Reason: "Failed", Message: `exec "<script>alert(1)</script>" failed`, Count: 1,
+	})
+	objects[0].(*v1.Pod).Status.ContainerStatuses = []v1.ContainerStatus{{Name: "app", Ready: true,
+		State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}}
+	sf, _ := newTestPlugin(objects...)
+	ctx := context.Background()
+	if err := sf.findPodByName(ctx, "web", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := sf.writeReportFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"<title>pod-lens: default/web-7d4b9-abcde</title>",
+		`<div class="graph"><svg xmlns="http://www.w3.org/2000/svg"`,
+		"<title>ConfigMap/default/web-config</title>",
+		"<summary>Relationship graph</summary>",
+		"<summary>Containers (1)</summary>",
+		"<tr><td>app</td><td>container</td><td>nginx</td><td>Running</td>",
+		"<summary>References (1)</summary>",
+		"<summary>Events (1)</summary>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report misses %q", want)
+		}
+	}
+	if strings.Contains(report, "<script>") {
+		t.Error("event message is not escaped")
+	}
+
+	if err := sf.writeReportFile(filepath.Join(t.TempDir(), "missing", "report.html")); err == nil {
+		t.Error("writing into a missing directory should fail")
+	}
+}
+
+func TestRenderGraphSVG(t *testing.T) {
+	pod := ObjectRef{Kind: "Pod", Namespace: testNamespace, Name: strings.Repeat("a", svgLabelChars+5)}
+	svc := ObjectRef{Kind: "Service", Namespace: testNamespace, Name: "web"}
+	svg := string(renderGraphSVG(newGraph([]Relationship{{From: svc, Type: "selects", To: pod}})))
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") || strings.Count(svg, "<rect") != 2 {
+		t.Errorf("svg = %s", svg)
+	}
+	if !strings.Contains(svg, ">selects</text>") || !strings.Contains(svg, "…</text>") {
+		t.Errorf("svg misses the edge label or the truncated name: %s", svg)
+	}
+}
@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pterm/pterm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const gatewayGroup = "gateway.networking.k8s.io"
+
+// routeResources lists the Gateway API route kinds pod-lens follows, each with
+// the versions to try in order so clusters on older CRD bundles still work.
+var routeResources = []struct {
+	Kind     string
+	Resource string
+	Versions []string
+}{
+	{Kind: "HTTPRoute", Resource: "httproutes", Versions: []string{"v1", "v1beta1"}},
+	{Kind: "GRPCRoute", Resource: "grpcroutes", Versions: []string{"v1", "v1alpha2"}},
+}
+
+type RouteParent struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type Route struct {
+	Kind      string        `json:"kind"`
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Hostnames []string      `json:"hostnames,omitempty"`
+	Parents   []RouteParent `json:"parents,omitempty"`
+	Backends  []string      `json:"backends"`
+}
+
+func (sf *SnifferPlugin) findRoutesByBackend(ctx context.Context, namespace string) error {
+	if sf.DynamicClient == nil {
+		return nil
+	}
+	services := sf.serviceNames()
+	for _, rr := range routeResources {
+		for _, version := range rr.Versions {
+			gvr := schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: rr.Resource}
+			routeFind, err := sf.DynamicClient.Resource(gvr).Namespace(namespace).List(
+				ctx, metav1.ListOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if sf.notVisible(rr.Resource, err) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			for _, item := range routeFind.Items {
+				route := parseRoute(rr.Kind, item)
+				for _, b := range route.Backends {
+					if services[b] {
+						sf.AllInfo.Routes = append(sf.AllInfo.Routes, route)
+						break
+					}
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (sf *SnifferPlugin) serviceNames() map[string]bool {
+	services := make(map[string]bool)
+	if sf.AllInfo.SvcList != nil {
+		for _, svc := range sf.AllInfo.SvcList.Items {
+			services[svc.Name] = true
+		}
+	}
+	return services
+}
+
+// parseRoute extracts the parents and same-namespace Service backends of an
+// HTTPRoute or GRPCRoute, which share the same shape for these fields.
+func parseRoute(kind string, u unstructured.Unstructured) Route {
+	route := Route{Kind: kind, Namespace: u.GetNamespace(), Name: u.GetName()}
+	route.Hostnames, _, _ = unstructured.NestedStringSlice(u.Object, "spec", "hostnames")
+
+	parents, _, _ := unstructured.NestedSlice(u.Object, "spec", "parentRefs")
+	for _, p := range parents {
+		ref, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parent := RouteParent{Kind: "Gateway", Namespace: route.Namespace}
+		if v, ok := ref["kind"].(string); ok && v != "" {
+			parent.Kind = v
+		}
+		if v, ok := ref["namespace"].(string); ok && v != "" {
+			parent.Namespace = v
+		}
+		parent.Name, _ = ref["name"].(string)
+		route.Parents = append(route.Parents, parent)
+	}
+
+	seen := make(map[string]bool)
+	rules, _, _ := unstructured.NestedSlice(u.Object, "spec", "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		refs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, b := range refs {
+			ref, ok := b.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if kind, ok := ref["kind"].(string); ok && kind != "" && kind != "Service" {
+				continue
+			}
+			if group, ok := ref["group"].(string); ok && group != "" {
+				continue
+			}
+			if ns, ok := ref["namespace"].(string); ok && ns != "" && ns != route.Namespace {
+				continue
+			}
+			name, _ := ref["name"].(string)
+			if name != "" && !seen[name] {
+				seen[name] = true
+				route.Backends = append(route.Backends, name)
+			}
+		}
+	}
+	return route
+}
+
+// trafficLeveledList renders the Ingress/Route -> Service -> Pod chains for the tree,
+// followed by any Service that is not fronted by an Ingress or Route.
+func (sf *SnifferPlugin) trafficLeveledList() []pterm.LeveledListItem {
+	var leveledList []pterm.LeveledListItem
+	services := sf.serviceNames()
+	fronted := make(map[string]bool)
+	addServices := func(backends []string) {
+		for _, b := range backends {
+			if !services[b] {
+				continue
+			}
+			fronted[b] = true
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
+				Text: cfmt.Sprintf("{{ [Service] }}::lightYellow|bold %s ➜ %s", b, sf.PodObject.Name)})
+		}
+	}
+	if sf.AllInfo.IngList != nil {
+		for _, ing := range sf.AllInfo.IngList.Items {
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
+				Text: cfmt.Sprintf("{{ [Ingress] }}::green|bold %s", ing.Name)})
+			addServices(ingressBackends(ing))
+		}
+	}
+	for _, route := range sf.AllInfo.Routes {
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
+			Text: cfmt.Sprintf("{{ [%s] }}::green|bold %s", route.Kind, route.Name)})
+		addServices(route.Backends)
+	}
+	if sf.AllInfo.SvcList != nil {
+		for _, svc := range sf.AllInfo.SvcList.Items {
+			if fronted[svc.Name] {
+				continue
+			}
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
+				Text: cfmt.Sprintf("{{ [Service] }}::lightYellow|bold %s ➜ %s", svc.Name, sf.PodObject.Name)})
+		}
+	}
+	return leveledList
+}
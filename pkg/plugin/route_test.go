@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testRoute(kind, version, name string, backends ...string) *unstructured.Unstructured {
+	var refs []interface{}
+	for _, b := range backends {
+		refs = append(refs, map[string]interface{}{"name": b, "port": int64(80)})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gatewayGroup + "/" + version,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": testNamespace},
+		"spec": map[string]interface{}{
+			"hostnames":  []interface{}{"web.example.com"},
+			"parentRefs": []interface{}{map[string]interface{}{"name": "public", "namespace": "gateways"}},
+			"rules":      []interface{}{map[string]interface{}{"backendRefs": refs}},
+		},
+	}}
+}
+
+func newTestDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for _, rr := range routeResources {
+		for _, version := range rr.Versions {
+			listKinds[schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: rr.Resource}] = rr.Kind + "List"
+		}
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func TestFindRoutesByBackend(t *testing.T) {
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		notServed  string
+		forbidden  string
+		want       []string
+		notVisible []NotVisible
+	}{
+		{
+			name: "http and grpc routes",
+			objects: []runtime.Object{
+				testRoute("HTTPRoute", "v1", "web", "web"),
+				testRoute("HTTPRoute", "v1", "db", "db"),
+				testRoute("GRPCRoute", "v1", "web-grpc", "db", "web"),
+			},
+			want: []string{"HTTPRoute/web", "GRPCRoute/web-grpc"},
+		},
+		{
+			name:      "falls back to older version",
+			objects:   []runtime.Object{testRoute("HTTPRoute", "v1beta1", "web", "web")},
+			notServed: "v1",
+			want:      []string{"HTTPRoute/web"},
+		},
+		{
+			name:       "forbidden",
+			objects:    []runtime.Object{testRoute("HTTPRoute", "v1", "web", "web")},
+			forbidden:  "httproutes",
+			notVisible: []NotVisible{{Resource: "httproutes", Reason: "forbidden"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamicClient := newTestDynamicClient(tt.objects...)
+			if tt.notServed != "" {
+				dynamicClient.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if action.GetResource().Version != tt.notServed {
+						return false, nil, nil
+					}
+					return true, nil, apierrors.NewNotFound(action.GetResource().GroupResource(), "")
+				})
+			}
+			if tt.forbidden != "" {
+				dynamicClient.PrependReactor("list", tt.forbidden, func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(action.GetResource().GroupResource(), "", nil)
+				})
+			}
+			sf, _ := newTestPlugin()
+			sf.DynamicClient = dynamicClient
+			sf.PodObject = testPod()
+			sf.AllInfo.SvcList = &v1.ServiceList{Items: []v1.Service{{ObjectMeta: objectMeta("web", nil)}}}
+			if err := sf.findRoutesByBackend(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range sf.AllInfo.Routes {
+				got = append(got, r.Kind+"/"+r.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findRoutesByBackend() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(sf.AllInfo.NotVisible, tt.notVisible) {
+				t.Errorf("NotVisible = %v, want %v", sf.AllInfo.NotVisible, tt.notVisible)
+			}
+		})
+	}
+}
+
+func TestParseRoute(t *testing.T) {
+	route := testRoute("HTTPRoute", "v1", "web", "web", "web", "api")
+	got := parseRoute("HTTPRoute", *route)
+	want := Route{
+		Kind:      "HTTPRoute",
+		Namespace: testNamespace,
+		Name:      "web",
+		Hostnames: []string{"web.example.com"},
+		Parents:   []RouteParent{{Kind: "Gateway", Namespace: "gateways", Name: "public"}},
+		Backends:  []string{"web", "api"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRoute() = %+v, want %+v", got, want)
+	}
+}
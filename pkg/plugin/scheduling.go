@@ -0,0 +1,314 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// SchedulingAnalysis explains why a pod has not been assigned to a node.
+type SchedulingAnalysis struct {
+	Issues []string  `json:"issues"`
+	Nodes  []NodeFit `json:"nodes"`
+}
+
+// NodeFit lists the reasons a single node cannot run the pod.
+type NodeFit struct {
+	Node    string   `json:"node"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+var nodeSelectorOperators = map[v1.NodeSelectorOperator]selection.Operator{
+	v1.NodeSelectorOpIn:           selection.In,
+	v1.NodeSelectorOpNotIn:        selection.NotIn,
+	v1.NodeSelectorOpExists:       selection.Exists,
+	v1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	v1.NodeSelectorOpGt:           selection.GreaterThan,
+	v1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func (sf *SnifferPlugin) analyzeScheduling(ctx context.Context, namespace string) error {
+	if sf.PodObject.Spec.NodeName != "" {
+		return nil
+	}
+	analysis := &SchedulingAnalysis{}
+
+	for _, e := range sf.AllInfo.Events {
+		if e.Object.Kind == "Pod" && e.Reason == "FailedScheduling" {
+			analysis.Issues = append(analysis.Issues, "FailedScheduling: "+e.Message)
+		}
+	}
+
+	for _, ref := range sf.AllInfo.References {
+		if ref.Kind != "PersistentVolumeClaim" || ref.Missing {
+			continue
+		}
+		pvc, err := sf.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || sf.notVisible("persistentvolumeclaims", err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if pvc.Status.Phase != v1.ClaimBound {
+			analysis.Issues = append(analysis.Issues,
+				fmt.Sprintf("PVC %s is %s, not Bound", pvc.Name, pvc.Status.Phase))
+		}
+	}
+
+	quotas, err := sf.Clientset.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !sf.notVisible("resourcequotas", err) {
+		return err
+	}
+	if err == nil {
+		requests, limits := podRequests(sf.PodObject), podLimits(sf.PodObject)
+		rejected := "new pods from the owner would be rejected"
+		if podOwner(sf.PodObject, nil).Kind == "Pod" {
+			rejected = "this pod would be rejected if recreated"
+		}
+		for _, q := range quotas.Items {
+			names := make([]string, 0, len(q.Status.Hard))
+			for name := range q.Status.Hard {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				demand, ok := quotaDemand(v1.ResourceName(name), requests, limits)
+				if !ok {
+					continue
+				}
+				hard, used := q.Status.Hard[v1.ResourceName(name)], q.Status.Used[v1.ResourceName(name)]
+				total := used.DeepCopy()
+				total.Add(demand)
+				if total.Cmp(hard) > 0 {
+					analysis.Issues = append(analysis.Issues, fmt.Sprintf("ResourceQuota %s has %s/%s %s used, %s",
+						q.Name, used.String(), hard.String(), name, rejected))
+				}
+			}
+		}
+	}
+
+	nodes, err := sf.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if sf.notVisible("nodes", err) {
+		sf.AllInfo.Scheduling = analysis
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pods, err := sf.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed"})
+	if sf.notVisible("pods", err) {
+		analysis.Issues = append(analysis.Issues, "cannot list pods in all namespaces, node capacity is not checked")
+		pods = &v1.PodList{}
+	} else if err != nil {
+		return err
+	}
+	requested := make(map[string]v1.ResourceList)
+	for _, p := range pods.Items {
+		if p.Spec.NodeName == "" || p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		requested[p.Spec.NodeName] = addResourceList(requested[p.Spec.NodeName], podRequests(&p))
+	}
+
+	podRequest := podRequests(sf.PodObject)
+	summary := make(map[string]int)
+	fits := 0
+	for _, node := range nodes.Items {
+		reasons := nodeMismatches(sf.PodObject, &node, podRequest, requested[node.Name])
+		for _, r := range reasons {
+			summary[r]++
+		}
+		if len(reasons) == 0 {
+			fits++
+		}
+		analysis.Nodes = append(analysis.Nodes, NodeFit{Node: node.Name, Reasons: reasons})
+	}
+	if len(nodes.Items) > 0 {
+		var parts []string
+		for r, n := range summary {
+			parts = append(parts, fmt.Sprintf("%d %s", n, r))
+		}
+		sort.Strings(parts)
+		msg := fmt.Sprintf("%d/%d nodes can run the pod", fits, len(nodes.Items))
+		if len(parts) > 0 {
+			msg += ": " + strings.Join(parts, ", ")
+		}
+		analysis.Issues = append(analysis.Issues, msg)
+	}
+
+	sf.AllInfo.Scheduling = analysis
+	return nil
+}
+
+// quotaDemand returns what a pod like this one counts against a quota
+// resource. Quota is checked when a pod is admitted, so only resources the pod
+// requests matter; object counts and storage are left out.
+func quotaDemand(name v1.ResourceName, requests, limits v1.ResourceList) (resource.Quantity, bool) {
+	var q resource.Quantity
+	switch s := string(name); {
+	case name == v1.ResourcePods:
+		q = requests[v1.ResourcePods]
+	case name == v1.ResourceCPU || name == v1.ResourceMemory:
+		q = requests[name]
+	case strings.HasPrefix(s, "requests."):
+		q = requests[v1.ResourceName(strings.TrimPrefix(s, "requests."))]
+	case strings.HasPrefix(s, "limits."):
+		q = limits[v1.ResourceName(strings.TrimPrefix(s, "limits."))]
+	}
+	return q, !q.IsZero()
+}
+
+// nodeMismatches returns the reasons the pod cannot be placed on the node,
+// following the same predicates as the default scheduler's filter plugins.
+func nodeMismatches(pod *v1.Pod, node *v1.Node, podRequest, nodeRequested v1.ResourceList) []string {
+	var reasons []string
+	if node.Spec.Unschedulable {
+		reasons = append(reasons, "unschedulable (cordoned)")
+	}
+	if len(pod.Spec.NodeSelector) > 0 &&
+		!labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		reasons = append(reasons, "nodeSelector mismatch")
+	}
+	if a := pod.Spec.Affinity; a != nil && a.NodeAffinity != nil &&
+		a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil &&
+		!nodeMatchesSelectorTerms(node, a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) {
+		reasons = append(reasons, "node affinity mismatch")
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect != v1.TaintEffectNoSchedule && taint.Effect != v1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			reasons = append(reasons, fmt.Sprintf("untolerated taint {%s}", taint.ToString()))
+		}
+	}
+	for name, req := range podRequest {
+		allocatable, ok := node.Status.Allocatable[name]
+		if !ok {
+			if !req.IsZero() {
+				reasons = append(reasons, fmt.Sprintf("no %s", name))
+			}
+			continue
+		}
+		free := allocatable.DeepCopy()
+		used := nodeRequested[name]
+		free.Sub(used)
+		if req.Cmp(free) > 0 {
+			reasons = append(reasons, fmt.Sprintf("insufficient %s", name))
+		}
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+func nodeMatchesSelectorTerms(node *v1.Node, terms []v1.NodeSelectorTerm) bool {
+	for _, term := range terms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+		if requirementsMatch(term.MatchExpressions, labels.Set(node.Labels)) &&
+			requirementsMatch(term.MatchFields, labels.Set{"metadata.name": node.Name}) {
+			return true
+		}
+	}
+	return false
+}
+
+func requirementsMatch(reqs []v1.NodeSelectorRequirement, set labels.Set) bool {
+	for _, req := range reqs {
+		op, ok := nodeSelectorOperators[req.Operator]
+		if !ok {
+			return false
+		}
+		r, err := labels.NewRequirement(req.Key, op, req.Values)
+		if err != nil || !r.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+// podRequests sums the container requests of a pod, taking the largest init
+// container request into account and counting the pod itself.
+func podRequests(pod *v1.Pod) v1.ResourceList {
+	reqs := podResources(pod, func(r v1.ResourceRequirements) v1.ResourceList { return r.Requests })
+	reqs[v1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+	return reqs
+}
+
+// podLimits sums the container limits of a pod the same way as podRequests.
+// Containers without a limit are not counted.
+func podLimits(pod *v1.Pod) v1.ResourceList {
+	return podResources(pod, func(r v1.ResourceRequirements) v1.ResourceList { return r.Limits })
+}
+
+func podResources(pod *v1.Pod, pick func(v1.ResourceRequirements) v1.ResourceList) v1.ResourceList {
+	total := v1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		total = addResourceList(total, pick(c.Resources))
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range pick(c.Resources) {
+			if cur, ok := total[name]; !ok || q.Cmp(cur) > 0 {
+				total[name] = q.DeepCopy()
+			}
+		}
+	}
+	return addResourceList(total, pod.Spec.Overhead)
+}
+
+func addResourceList(list, add v1.ResourceList) v1.ResourceList {
+	if list == nil {
+		list = v1.ResourceList{}
+	}
+	for name, q := range add {
+		cur := list[name]
+		cur.Add(q)
+		list[name] = cur
+	}
+	return list
+}
+
+func (sf *SnifferPlugin) printScheduling() {
+	analysis := sf.AllInfo.Scheduling
+	if analysis == nil {
+		return
+	}
+	table := uitable.New()
+	table.Wrap = true
+	table.AddRow("")
+	for _, issue := range analysis.Issues {
+		table.AddRow("Issue:", pterm.Red(issue))
+	}
+	table.AddRow("---", "---")
+	for _, n := range analysis.Nodes {
+		if len(n.Reasons) == 0 {
+			table.AddRow(n.Node, pterm.Green("fits"))
+			continue
+		}
+		table.AddRow(n.Node, pterm.Yellow(strings.Join(n.Reasons, ", ")))
+	}
+	_, _ = cfmt.Println("{{ Scheduling Analysis }}::bgRed|#ffffff")
+	fmt.Println(table)
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testNode(name string, cpu string, mutate func(*v1.Node)) *v1.Node {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/hostname": name}},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse("4Gi"),
+			v1.ResourcePods:   resource.MustParse("110"),
+		}},
+	}
+	if mutate != nil {
+		mutate(node)
+	}
+	return node
+}
+
+func pendingPod() *v1.Pod {
+	pod := testPod()
+	pod.Spec.NodeName = ""
+	pod.Status.Phase = v1.PodPending
+	pod.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}
+	return pod
+}
+
+func TestAnalyzeScheduling(t *testing.T) {
+	running := testPod()
+	running.Name = "busy"
+	running.Spec.NodeName = "small"
+	running.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1500m")}
+
+	tests := []struct {
+		name    string
+		pod     *v1.Pod
+		objects []runtime.Object
+		want    []NodeFit
+	}{
+		{
+			name:    "already scheduled",
+			pod:     testPod(),
+			objects: []runtime.Object{testNode("node-1", "2", nil)},
+		},
+		{
+			name: "cordoned, tainted and full nodes",
+			pod:  pendingPod(),
+			objects: []runtime.Object{
+				running,
+				testNode("fits", "2", nil),
+				testNode("small", "2", nil),
+				testNode("cordoned", "2", func(n *v1.Node) { n.Spec.Unschedulable = true }),
+				testNode("tainted", "2", func(n *v1.Node) {
+					n.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+				}),
+			},
+			want: []NodeFit{
+				{Node: "cordoned", Reasons: []string{"unschedulable (cordoned)"}},
+				{Node: "fits"},
+				{Node: "small", Reasons: []string{"insufficient cpu"}},
+				{Node: "tainted", Reasons: []string{"untolerated taint {dedicated=gpu:NoSchedule}"}},
+			},
+		},
+		{
+			name: "node selector and affinity",
+			pod: func() *v1.Pod {
+				pod := pendingPod()
+				pod.Spec.NodeSelector = map[string]string{"disk": "ssd"}
+				pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}},
+						}}}}}}
+				return pod
+			}(),
+			objects: []runtime.Object{
+				testNode("ssd-a", "2", func(n *v1.Node) { n.Labels["disk"], n.Labels["zone"] = "ssd", "a" }),
+				testNode("ssd-b", "2", func(n *v1.Node) { n.Labels["disk"], n.Labels["zone"] = "ssd", "b" }),
+				testNode("hdd-a", "2", func(n *v1.Node) { n.Labels["disk"], n.Labels["zone"] = "hdd", "a" }),
+			},
+			want: []NodeFit{
+				{Node: "hdd-a", Reasons: []string{"nodeSelector mismatch"}},
+				{Node: "ssd-a"},
+				{Node: "ssd-b", Reasons: []string{"node affinity mismatch"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(tt.objects...)
+			sf.PodObject = tt.pod
+			if err := sf.analyzeScheduling(context.Background(), testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if sf.AllInfo.Scheduling != nil {
+					t.Errorf("analyzeScheduling() = %+v, want nil", sf.AllInfo.Scheduling)
+				}
+				return
+			}
+			if !reflect.DeepEqual(sf.AllInfo.Scheduling.Nodes, tt.want) {
+				t.Errorf("analyzeScheduling() nodes = %+v, want %+v", sf.AllInfo.Scheduling.Nodes, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSchedulingIssues(t *testing.T) {
+	pod := pendingPod()
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: objectMeta("web-data", nil),
+		Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimPending}}
+	quota := &v1.ResourceQuota{ObjectMeta: objectMeta("compute", nil), Status: v1.ResourceQuotaStatus{
+		Hard: v1.ResourceList{v1.ResourcePods: resource.MustParse("10"), v1.ResourceRequestsCPU: resource.MustParse("4"),
+			v1.ResourceLimitsMemory: resource.MustParse("0")},
+		Used: v1.ResourceList{v1.ResourcePods: resource.MustParse("10"), v1.ResourceRequestsCPU: resource.MustParse("3500m")},
+	}}
+	unrelated := &v1.ResourceQuota{ObjectMeta: objectMeta("objects", nil), Status: v1.ResourceQuotaStatus{
+		Hard: v1.ResourceList{v1.ResourceServices: resource.MustParse("2"), v1.ResourceRequestsStorage: resource.MustParse("0"),
+			"count/jobs.batch": resource.MustParse("5")},
+		Used: v1.ResourceList{v1.ResourceServices: resource.MustParse("2"), "count/jobs.batch": resource.MustParse("5")},
+	}}
+	sf, _ := newTestPlugin(pvc, quota, unrelated, testNode("node-1", "2", nil))
+	sf.PodObject = pod
+	sf.AllInfo.References = []Reference{{Kind: "PersistentVolumeClaim", Name: "web-data", Via: ViaVolume}}
+	sf.AllInfo.Events = []TimelineEvent{{Object: ObjectRef{Kind: "Pod"}, Reason: "FailedScheduling", Message: "0/1 nodes are available"}}
+	if err := sf.analyzeScheduling(context.Background(), testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"FailedScheduling: 0/1 nodes are available",
+		"PVC web-data is Pending, not Bound",
+		"ResourceQuota compute has 10/10 pods used, new pods from the owner would be rejected",
+		"ResourceQuota compute has 3500m/4 requests.cpu used, new pods from the owner would be rejected",
+		"1/1 nodes can run the pod",
+	}
+	if !reflect.DeepEqual(sf.AllInfo.Scheduling.Issues, want) {
+		t.Errorf("analyzeScheduling() issues = %q, want %q", sf.AllInfo.Scheduling.Issues, want)
+	}
+
+	pod.OwnerReferences = nil
+	sf.AllInfo.References, sf.AllInfo.Events = nil, nil
+	if err := sf.analyzeScheduling(context.Background(), testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"ResourceQuota compute has 10/10 pods used, this pod would be rejected if recreated",
+		"ResourceQuota compute has 3500m/4 requests.cpu used, this pod would be rejected if recreated",
+		"1/1 nodes can run the pod",
+	}
+	if !reflect.DeepEqual(sf.AllInfo.Scheduling.Issues, want) {
+		t.Errorf("bare pod issues = %q, want %q", sf.AllInfo.Scheduling.Issues, want)
+	}
+}
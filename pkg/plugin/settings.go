@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const redactedValue = "<redacted>"
+
+// ResourceTypes are the related-resource lookups that can be switched off.
+// The pod, its node and its owner are always looked up.
+var ResourceTypes = []string{
+	"deployments",
+	"statefulsets",
+	"daemonsets",
+	"services",
+	"ingresses",
+	"routes",
+	"persistentvolumeclaims",
+	"configmaps",
+	"secrets",
+	"references",
+	"horizontalpodautoscalers",
+	"poddisruptionbudgets",
+	"events",
+}
+
+// setResources limits the lookups to the given resource types; an empty list
+// enables all of them.
+func (sf *SnifferPlugin) setResources(enabled []string) error {
+	if len(enabled) == 0 {
+		sf.resources = nil
+		return nil
+	}
+	known := make(map[string]bool, len(ResourceTypes))
+	for _, r := range ResourceTypes {
+		known[r] = true
+	}
+	sf.resources = make(map[string]bool, len(enabled))
+	for _, r := range enabled {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if !known[r] {
+			return errors.Errorf("unknown resource type %q, expected any of %s", r, strings.Join(ResourceTypes, ", "))
+		}
+		sf.resources[r] = true
+	}
+	return nil
+}
+
+func (sf *SnifferPlugin) enabled(resource string) bool {
+	return sf.resources == nil || sf.resources[resource]
+}
+
+// ifEnabled returns t, or nil when the resource type is switched off.
+func (sf *SnifferPlugin) ifEnabled(resource string, t task) task {
+	if !sf.enabled(resource) {
+		return nil
+	}
+	return t
+}
+
+// lookup runs a single resource type lookup when it is enabled, tolerating
+// errors that only hide that type.
+func (sf *SnifferPlugin) lookup(resource string, t task) task {
+	return sf.ifEnabled(resource, sf.partial(resource, t))
+}
+
+// setRedactRules compiles the patterns matched against env var names,
+// annotation keys and ConfigMap keys whose values are hidden in -o json|yaml.
+func (sf *SnifferPlugin) setRedactRules(patterns []string) error {
+	sf.redact = nil
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return errors.Errorf("invalid redact rule %q: %v", p, err)
+		}
+		sf.redact = append(sf.redact, re)
+	}
+	return nil
+}
+
+func (sf *SnifferPlugin) redacted(name string) bool {
+	for _, re := range sf.redact {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactMap returns a copy of m with the values of matching keys hidden, so
+// the objects held by the plugin are left untouched.
+func (sf *SnifferPlugin) redactMap(m map[string]string) map[string]string {
+	if len(sf.redact) == 0 || len(m) == 0 {
+		return m
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if sf.redacted(k) {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// cleanMeta drops managed fields and the last-applied configuration, which
+// repeats the spec and data unredacted, and redacts annotations.
+func (sf *SnifferPlugin) cleanMeta(meta *metav1.ObjectMeta) {
+	stripManagedFields(meta)
+	if _, ok := meta.Annotations[lastAppliedAnnotation]; ok {
+		annotations := make(map[string]string, len(meta.Annotations))
+		for k, v := range meta.Annotations {
+			if k != lastAppliedAnnotation {
+				annotations[k] = v
+			}
+		}
+		meta.Annotations = annotations
+	}
+	meta.Annotations = sf.redactMap(meta.Annotations)
+}
+
+// redactPod hides matching env var values of a pod that is owned by the caller.
+func (sf *SnifferPlugin) redactPod(pod *v1.Pod) {
+	sf.redactPodSpec(&pod.Spec)
+}
+
+// redactPodSpec hides matching env var values of a pod spec or workload
+// template that is owned by the caller.
+func (sf *SnifferPlugin) redactPodSpec(spec *v1.PodSpec) {
+	if len(sf.redact) == 0 {
+		return
+	}
+	redactEnv := func(env []v1.EnvVar) {
+		for i := range env {
+			if env[i].Value != "" && sf.redacted(env[i].Name) {
+				env[i].Value = redactedValue
+			}
+		}
+	}
+	for i := range spec.InitContainers {
+		redactEnv(spec.InitContainers[i].Env)
+	}
+	for i := range spec.Containers {
+		redactEnv(spec.Containers[i].Env)
+	}
+	for i := range spec.EphemeralContainers {
+		redactEnv(spec.EphemeralContainers[i].Env)
+	}
+}
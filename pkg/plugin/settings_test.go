@@ -0,0 +1,117 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSetResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled []string
+		wantErr bool
+		on      []string
+		off     []string
+	}{
+		{name: "all by default", on: ResourceTypes},
+		{name: "subset", enabled: []string{"Services", " events "}, on: []string{"services", "events"}, off: []string{"secrets", "routes"}},
+		{name: "unknown type", enabled: []string{"services", "pods"}, wantErr: true},
+	}
+	noop := func(ctx context.Context) error { return nil }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin()
+			err := sf.setResources(tt.enabled)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, r := range tt.on {
+				if sf.ifEnabled(r, noop) == nil {
+					t.Errorf("%s should be enabled", r)
+				}
+			}
+			for _, r := range tt.off {
+				if sf.ifEnabled(r, noop) != nil {
+					t.Errorf("%s should be disabled", r)
+				}
+			}
+		})
+	}
+}
+
+func TestFindRelatedDisabledResources(t *testing.T) {
+	sf, _ := newTestPlugin(testCluster()...)
+	ctx := context.Background()
+	if err := sf.findPodByName(ctx, "web", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.setResources([]string{"services"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if sf.AllInfo.SvcList == nil || len(sf.AllInfo.SvcList.Items) != 1 {
+		t.Errorf("services = %v, want 1", sf.AllInfo.SvcList)
+	}
+	if sf.AllInfo.DeployList != nil || sf.AllInfo.SecretList != nil || sf.AllInfo.References != nil {
+		t.Error("disabled resource types should not be looked up")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	sf, _ := newTestPlugin()
+	if err := sf.setRedactRules([]string{"(?i)password", "["}); err == nil {
+		t.Fatal("invalid rule should fail")
+	}
+	if err := sf.setRedactRules([]string{"(?i)password|token"}); err != nil {
+		t.Fatal(err)
+	}
+	pod := testPod()
+	pod.Annotations = map[string]string{"api-token": "abc", "owner": "team-a"}
+	pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: "DB_PASSWORD", Value: "hunter2"}, {Name: "LOG_LEVEL", Value: "info"}}
+	sf.PodObject = pod
+	sf.AllInfo.ConfigMapList = &v1.ConfigMapList{Items: []v1.ConfigMap{{ObjectMeta: objectMeta("web-config", nil),
+		Data: map[string]string{"admin_password": "secret", "port": "80"}}}}
+	deploy := appsv1.Deployment{ObjectMeta: objectMeta("web", map[string]string{"app": "web"})}
+	deploy.Annotations = map[string]string{lastAppliedAnnotation: `{"env":[{"name":"DB_PASSWORD","value":"hunter2"}]}`}
+	deploy.Spec.Template.Spec = *pod.Spec.DeepCopy()
+	sf.AllInfo.DeployList = &appsv1.DeploymentList{Items: []appsv1.Deployment{deploy}}
+
+	doc := sf.buildDocument()
+	if got := doc.Pod.Annotations["api-token"]; got != redactedValue {
+		t.Errorf("annotation = %q, want redacted", got)
+	}
+	if got := doc.Pod.Annotations["owner"]; got != "team-a" {
+		t.Errorf("annotation = %q, want team-a", got)
+	}
+	env := doc.Pod.Spec.Containers[0].Env
+	if env[0].Value != redactedValue || env[1].Value != "info" {
+		t.Errorf("env = %+v", env)
+	}
+	data := doc.ConfigMaps[0].Data
+	if data["admin_password"] != redactedValue || data["port"] != "80" {
+		t.Errorf("configmap data = %v", data)
+	}
+	template := doc.Deployments[0].Spec.Template.Spec.Containers[0].Env
+	if template[0].Value != redactedValue || template[1].Value != "info" {
+		t.Errorf("deployment template env = %+v", template)
+	}
+	if _, ok := doc.Deployments[0].Annotations[lastAppliedAnnotation]; ok {
+		t.Error("last-applied configuration should not be emitted")
+	}
+	if deploy := sf.AllInfo.DeployList.Items[0]; deploy.Spec.Template.Spec.Containers[0].Env[0].Value != "hunter2" ||
+		deploy.Annotations[lastAppliedAnnotation] == "" {
+		t.Error("redaction should not modify the deployments held by the plugin")
+	}
+	if pod.Spec.Containers[0].Env[0].Value != "hunter2" || pod.Annotations["api-token"] != "abc" ||
+		sf.AllInfo.ConfigMapList.Items[0].Data["admin_password"] != "secret" {
+		t.Error("redaction should not modify the objects held by the plugin")
+	}
+}
@@ -0,0 +1,631 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"atomicgo.dev/keyboard"
+	"atomicgo.dev/keyboard/keys"
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/mattn/go-runewidth"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+type tuiPane int
+
+const (
+	paneDetails tuiPane = iota
+	paneYAML
+	paneEvents
+	paneLogs
+	paneDescribe
+)
+
+var paneNames = []string{"Details", "YAML", "Events", "Logs", "Describe"}
+
+// tuiLogLines is how much of each container's log the logs pane shows.
+const tuiLogLines = 200
+
+const tuiHelp = "↑↓ move  enter open pod  b back  ←→ tab i y e l d pane  pgup pgdn scroll  r refresh  q quit"
+
+var (
+	tuiSelectedStyle = pterm.NewStyle(pterm.BgCyan, pterm.FgBlack)
+	tuiHeaderStyle   = pterm.NewStyle(pterm.BgCyan, pterm.FgWhite, pterm.Bold)
+)
+
+// tuiItem is one line of the navigable relationship tree.
+type tuiItem struct {
+	ref   ObjectRef
+	depth int
+	rel   string
+}
+
+// tuiView is the tree of one pod or workload, kept to go back to it.
+type tuiView struct {
+	sf     *SnifferPlugin
+	items  []tuiItem
+	cursor int
+}
+
+type paneKey struct {
+	ref  ObjectRef
+	pane tuiPane
+}
+
+// lensTUI is the state of --tui. It renders frames to strings so that only
+// runTUI touches the terminal.
+type lensTUI struct {
+	tuiView
+	opts    Options
+	timeout time.Duration
+	history []tuiView
+	top     int
+	pane    tuiPane
+	scroll  int
+	cache   map[paneKey][]string
+	status  string
+	// logs is off offline, manifests carry no container output.
+	logs bool
+	// describe runs `kubectl describe`, the pane falls back to the details
+	// and events when it is nil.
+	describe      func(ctx context.Context, ref ObjectRef) (string, error)
+	width, height int
+	now           func() time.Time
+}
+
+func newLensTUI(ctx context.Context, sf *SnifferPlugin, timeout time.Duration, opts Options) (*lensTUI, error) {
+	t := &lensTUI{opts: opts, timeout: timeout, logs: !opts.offline(), width: 120, height: 40, now: time.Now}
+	if err := t.open(ctx, sf); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *lensTUI) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.timeout > 0 {
+		return context.WithTimeout(ctx, t.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// open looks up everything related to the pod or workload of sf and shows
+// its tree.
+func (t *lensTUI) open(ctx context.Context, sf *SnifferPlugin) error {
+	ctx, cancel := t.requestContext(ctx)
+	defer cancel()
+	if err := sf.findRelated(ctx, t.opts); err != nil {
+		return contextError(ctx, t.timeout, err)
+	}
+	siblings, err := sf.siblingPods(ctx)
+	if err != nil {
+		return contextError(ctx, t.timeout, err)
+	}
+	t.tuiView = tuiView{sf: sf, items: sf.tuiItems(siblings)}
+	t.cache = make(map[paneKey][]string)
+	t.top, t.scroll = 0, 0
+	return nil
+}
+
+// siblingPods returns the other pods of the selected pod's controller.
+func (sf *SnifferPlugin) siblingPods(ctx context.Context) ([]v1.Pod, error) {
+	own := podOwner(sf.PodObject, nil)
+	if sf.fromWorkload || own.Kind == "Pod" || sf.AllInfo.Workload.Name == "" {
+		return nil, nil
+	}
+	pods, err := sf.Clientset.CoreV1().Pods(sf.PodObject.Namespace).List(ctx, metav1.ListOptions{})
+	if sf.notVisible("pods", err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sibling pods")
+	}
+	var siblings []v1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Name != sf.PodObject.Name && podOwner(&pods.Items[i], nil) == own {
+			siblings = append(siblings, pods.Items[i])
+		}
+	}
+	return siblings, nil
+}
+
+// tuiItems lays the relationships out as a tree from the pod or workload,
+// each object under the closest object it relates to.
+func (sf *SnifferPlugin) tuiItems(siblings []v1.Pod) []tuiItem {
+	rels := sf.buildRelationships()
+	for i := range siblings {
+		rels = append(rels, Relationship{From: sf.workloadRef(), To: newObjectRef("Pod", &siblings[i]), Type: "manages"})
+	}
+	root := newObjectRef("Pod", sf.PodObject)
+	if sf.fromWorkload {
+		root = sf.workloadRef()
+	}
+	// Objects only found by label come last, so a reference wins over them.
+	shared := func(kind string, meta metav1.Object) {
+		rels = append(rels, Relationship{From: newObjectRef(kind, meta), To: root, Type: "shares-label"})
+	}
+	if l := sf.AllInfo.DeployList; l != nil {
+		for i := range l.Items {
+			shared("Deployment", &l.Items[i])
+		}
+	}
+	if l := sf.AllInfo.StsList; l != nil {
+		for i := range l.Items {
+			shared("StatefulSet", &l.Items[i])
+		}
+	}
+	if l := sf.AllInfo.DsList; l != nil {
+		for i := range l.Items {
+			shared("DaemonSet", &l.Items[i])
+		}
+	}
+	if l := sf.AllInfo.PvcList; l != nil {
+		for i := range l.Items {
+			shared("PersistentVolumeClaim", &l.Items[i])
+		}
+	}
+	if l := sf.AllInfo.ConfigMapList; l != nil {
+		for i := range l.Items {
+			shared("ConfigMap", &l.Items[i])
+		}
+	}
+	if l := sf.AllInfo.SecretList; l != nil {
+		for i := range l.Items {
+			shared("Secret", &l.Items[i])
+		}
+	}
+	adjacent := make(map[ObjectRef][]Relationship)
+	for _, rel := range rels {
+		adjacent[rel.From] = append(adjacent[rel.From], rel)
+		adjacent[rel.To] = append(adjacent[rel.To], rel)
+	}
+
+	children := make(map[ObjectRef][]tuiItem)
+	seen := map[ObjectRef]bool{root: true}
+	for queue := []ObjectRef{root}; len(queue) > 0; queue = queue[1:] {
+		for _, rel := range adjacent[queue[0]] {
+			next := rel.To
+			if next == queue[0] {
+				next = rel.From
+			}
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			children[queue[0]] = append(children[queue[0]], tuiItem{ref: next, rel: rel.Type})
+			queue = append(queue, next)
+		}
+	}
+	var items []tuiItem
+	var walk func(item tuiItem, depth int)
+	walk = func(item tuiItem, depth int) {
+		item.depth = depth
+		items = append(items, item)
+		for _, child := range children[item.ref] {
+			walk(child, depth+1)
+		}
+	}
+	walk(tuiItem{ref: root}, 0)
+	return items
+}
+
+func (t *lensTUI) selected() ObjectRef {
+	return t.items[t.cursor].ref
+}
+
+func (t *lensTUI) bodyHeight() int {
+	if t.height < 4 {
+		return 1
+	}
+	return t.height - 2
+}
+
+func (t *lensTUI) move(delta int) {
+	t.cursor += delta
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+	if t.cursor >= len(t.items) {
+		t.cursor = len(t.items) - 1
+	}
+	t.scroll = 0
+}
+
+func (t *lensTUI) setPane(pane tuiPane) {
+	t.pane = tuiPane((int(pane) + len(paneNames)) % len(paneNames))
+	t.scroll = 0
+}
+
+// handle applies a key press and reports whether to quit.
+func (t *lensTUI) handle(ctx context.Context, key keys.Key) bool {
+	t.status = ""
+	page := t.bodyHeight() - 1
+	switch key.Code {
+	case keys.CtrlC, keys.Escape:
+		return true
+	case keys.Up:
+		t.move(-1)
+	case keys.Down:
+		t.move(1)
+	case keys.Home:
+		t.move(-len(t.items))
+	case keys.End:
+		t.move(len(t.items))
+	case keys.PgUp:
+		t.scroll -= page
+	case keys.PgDown, keys.Space:
+		t.scroll += page
+	case keys.Right, keys.Tab:
+		t.setPane(t.pane + 1)
+	case keys.Left, keys.ShiftTab:
+		t.setPane(t.pane - 1)
+	case keys.Enter:
+		t.enter(ctx)
+	case keys.Backspace:
+		t.back()
+	case keys.RuneKey:
+		if key.AltPressed {
+			return false
+		}
+		// Keys typed faster than a frame arrive together.
+		for _, r := range key.Runes {
+			switch r {
+			case 'q':
+				return true
+			case 'j':
+				t.move(1)
+			case 'k':
+				t.move(-1)
+			case 'g':
+				t.move(-len(t.items))
+			case 'G':
+				t.move(len(t.items))
+			case 'i':
+				t.setPane(paneDetails)
+			case 'y':
+				t.setPane(paneYAML)
+			case 'e':
+				t.setPane(paneEvents)
+			case 'l':
+				t.setPane(paneLogs)
+			case 'd':
+				t.setPane(paneDescribe)
+			case 'b':
+				t.back()
+			case 'r':
+				t.refresh(ctx)
+			}
+		}
+	}
+	if t.scroll < 0 {
+		t.scroll = 0
+	}
+	return false
+}
+
+// enter opens the lens of a pod picked in the tree, such as a sibling pod
+// or a pod of the workload.
+func (t *lensTUI) enter(ctx context.Context) {
+	ref := t.selected()
+	if ref.Kind != "Pod" || t.cursor == 0 {
+		t.setPane(paneDetails)
+		return
+	}
+	previous := t.tuiView
+	if err := t.openPod(ctx, ref); err != nil {
+		t.status = err.Error()
+		return
+	}
+	t.history = append(t.history, previous)
+}
+
+func (t *lensTUI) back() {
+	if len(t.history) == 0 {
+		t.status = "Nothing to go back to"
+		return
+	}
+	t.tuiView = t.history[len(t.history)-1]
+	t.history = t.history[:len(t.history)-1]
+	t.cache = make(map[paneKey][]string)
+	t.top, t.scroll = 0, 0
+}
+
+func (t *lensTUI) openPod(ctx context.Context, ref ObjectRef) error {
+	sf := NewSnifferPluginForClients(t.sf.Clientset, t.sf.DynamicClient)
+	sf.resources, sf.redact = t.sf.resources, t.sf.redact
+	getCtx, cancel := t.requestContext(ctx)
+	pod, err := sf.Clientset.CoreV1().Pods(ref.Namespace).Get(getCtx, ref.Name, metav1.GetOptions{})
+	cancel()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get pod %s", ref.Name)
+	}
+	sf.PodObject = pod
+	if err = sf.getLabelByPod(t.opts.LabelSelector, t.opts.LabelKeys); err != nil {
+		return err
+	}
+	return t.open(ctx, sf)
+}
+
+// refresh looks the tree up again and drops the loaded panes.
+func (t *lensTUI) refresh(ctx context.Context) {
+	root, cursor := t.items[0].ref, t.cursor
+	var err error
+	if t.sf.fromWorkload {
+		sf := NewSnifferPluginForClients(t.sf.Clientset, t.sf.DynamicClient)
+		sf.resources, sf.redact = t.sf.resources, t.sf.redact
+		getCtx, cancel := t.requestContext(ctx)
+		err = sf.findWorkload(getCtx, root.Kind, root.Name, root.Namespace)
+		cancel()
+		if err == nil {
+			if err = sf.getLabelByPod(t.opts.LabelSelector, t.opts.LabelKeys); err == nil {
+				err = t.open(ctx, sf)
+			}
+		}
+	} else {
+		err = t.openPod(ctx, root)
+	}
+	if err != nil {
+		t.status = err.Error()
+		return
+	}
+	t.move(cursor)
+}
+
+// content returns the side pane lines of the selected object, loading them
+// on first use.
+func (t *lensTUI) content(ctx context.Context) []string {
+	key := paneKey{ref: t.selected(), pane: t.pane}
+	if lines, ok := t.cache[key]; ok {
+		return lines
+	}
+	ctx, cancel := t.requestContext(ctx)
+	defer cancel()
+	text, err := t.load(ctx, key)
+	if err != nil {
+		text = "Error: " + contextError(ctx, t.timeout, err).Error()
+	}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\t", "    "), "\n"), "\n")
+	t.cache[key] = lines
+	return lines
+}
+
+func (t *lensTUI) load(ctx context.Context, key paneKey) (string, error) {
+	ref := key.ref
+	switch key.pane {
+	case paneYAML:
+		obj, err := t.sf.getObject(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		return t.sf.objectYAML(obj)
+	case paneEvents:
+		return t.eventsText(ctx, ref)
+	case paneLogs:
+		return t.logsText(ctx, ref)
+	case paneDescribe:
+		if t.describe != nil {
+			return t.describe(ctx, ref)
+		}
+		details, err := t.detailsText(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		events, err := t.eventsText(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		return details + "\nEvents:\n" + events, nil
+	}
+	return t.detailsText(ctx, ref)
+}
+
+func (t *lensTUI) detailsText(ctx context.Context, ref ObjectRef) (string, error) {
+	obj, err := t.sf.getObject(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%-16s %s\n", "Kind:", ref.Kind) + strings.Join(objectDetails(obj, t.now()), "\n"), nil
+}
+
+func (t *lensTUI) eventsText(ctx context.Context, ref ObjectRef) (string, error) {
+	events, err := t.sf.objectEvents(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if len(events) == 0 {
+		return "No events.", nil
+	}
+	now := t.now()
+	var b strings.Builder
+	for _, e := range mergeEvents(events) {
+		_, _ = fmt.Fprintf(&b, "%-5s %-8s %-20s x%-3d %s\n",
+			translateAge(e.LastSeen, now), e.Type, e.Reason, e.Count, e.Message)
+	}
+	return b.String(), nil
+}
+
+func (t *lensTUI) logsText(ctx context.Context, ref ObjectRef) (string, error) {
+	if ref.Kind != "Pod" {
+		return "Logs are shown for pods, select one in the tree.", nil
+	}
+	if !t.logs {
+		return "Logs need a cluster, manifests do not carry them.", nil
+	}
+	pod, err := t.sf.Clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	tail := int64(tuiLogLines)
+	for _, c := range pod.Spec.Containers {
+		_, _ = fmt.Fprintf(&b, "==> %s <==\n", c.Name)
+		lines, err := t.sf.containerLog(ctx, pod, v1.PodLogOptions{Container: c.Name, TailLines: &tail})
+		if err != nil {
+			_, _ = fmt.Fprintf(&b, "Error: %v\n", err)
+			continue
+		}
+		for _, line := range lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+func fitWidth(s string, width int) string {
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
+
+func (t *lensTUI) itemLine(n, width int) string {
+	item := t.items[n]
+	label := runewidth.Truncate(strings.Repeat("  ", item.depth)+item.ref.Kind+"/"+item.ref.Name, width, "…")
+	rel := ""
+	if item.rel != "" && runewidth.StringWidth(label)+len(item.rel)+1 <= width {
+		rel = " " + item.rel
+	}
+	pad := strings.Repeat(" ", width-runewidth.StringWidth(label+rel))
+	if n == t.cursor {
+		return tuiSelectedStyle.Sprint(label + rel + pad)
+	}
+	if style, ok := kindStyles[item.ref.Kind]; ok {
+		label = cfmt.Sprintf("{{%s}}::"+style, label)
+	}
+	return label + pterm.Gray(rel) + pad
+}
+
+func (t *lensTUI) tabs() string {
+	var b strings.Builder
+	for i, name := range paneNames {
+		if tuiPane(i) == t.pane {
+			b.WriteString(tuiSelectedStyle.Sprint(" " + name + " "))
+		} else {
+			b.WriteString(pterm.Gray(" " + name + " "))
+		}
+	}
+	return b.String()
+}
+
+// render draws a full frame: the tree on the left, the pane of the selected
+// object on the right and the keys at the bottom.
+func (t *lensTUI) render(ctx context.Context) string {
+	width := t.width
+	if width < 40 {
+		width = 40
+	}
+	left := width * 2 / 5
+	if left > 60 {
+		left = 60
+	}
+	right := width - left - 3
+	body := t.bodyHeight()
+	if t.cursor < t.top {
+		t.top = t.cursor
+	} else if t.cursor >= t.top+body {
+		t.top = t.cursor - body + 1
+	}
+	content := t.content(ctx)
+	if last := len(content) - (body - 1); t.scroll > last {
+		t.scroll = last
+	}
+	if t.scroll < 0 {
+		t.scroll = 0
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	header := fmt.Sprintf(" pod-lens  %s  %d/%d", t.items[0].ref, t.cursor+1, len(t.items))
+	b.WriteString(tuiHeaderStyle.Sprint(fitWidth(header, width)))
+	for i := 0; i < body; i++ {
+		line := strings.Repeat(" ", left)
+		if n := t.top + i; n < len(t.items) {
+			line = t.itemLine(n, left)
+		}
+		line += pterm.Gray(" │ ")
+		if i == 0 {
+			line += t.tabs()
+		} else if n := t.scroll + i - 1; n < len(content) {
+			line += runewidth.Truncate(content[n], right, "…")
+		}
+		b.WriteString("\x1b[K\r\n" + line)
+	}
+	footer := pterm.Gray(fitWidth(tuiHelp, width))
+	if t.status != "" {
+		footer = pterm.Red(fitWidth(t.status, width))
+	}
+	b.WriteString("\x1b[K\r\n" + footer + "\x1b[K\x1b[J")
+	return b.String()
+}
+
+// kubectlDescribe runs `kubectl describe` with the kubeconfig flags this run
+// was given. It is nil when kubectl is not on the PATH.
+func kubectlDescribe(configFlags *genericclioptions.ConfigFlags) func(context.Context, ObjectRef) (string, error) {
+	kubectl, err := exec.LookPath("kubectl")
+	if err != nil {
+		return nil
+	}
+	var global []string
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{
+		{"kubeconfig", configFlags.KubeConfig},
+		{"context", configFlags.Context},
+		{"cluster", configFlags.ClusterName},
+		{"user", configFlags.AuthInfoName},
+		{"server", configFlags.APIServer},
+	} {
+		if f.value != nil && *f.value != "" {
+			global = append(global, "--"+f.name+"="+*f.value)
+		}
+	}
+	return func(ctx context.Context, ref ObjectRef) (string, error) {
+		args := append([]string{"describe", strings.ToLower(ref.Kind), ref.Name}, global...)
+		if ref.Namespace != "" {
+			args = append(args, "--namespace="+ref.Namespace)
+		}
+		out, err := exec.CommandContext(ctx, kubectl, args...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return "", errors.New(msg)
+			}
+			return "", errors.Wrap(err, "kubectl describe failed")
+		}
+		return string(out), nil
+	}
+}
+
+// runTUI shows the relationship tree full screen, with the details, YAML,
+// events, logs or description of the selected object in a side pane.
+func (sf *SnifferPlugin) runTUI(ctx context.Context, timeout time.Duration, opts Options,
+	describe func(context.Context, ObjectRef) (string, error)) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("--tui needs an interactive terminal")
+	}
+	t, err := newLensTUI(ctx, sf, timeout, opts)
+	if err != nil {
+		return err
+	}
+	t.describe = describe
+
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+	draw := func() {
+		t.width, t.height = pterm.GetTerminalWidth(), pterm.GetTerminalHeight()
+		fmt.Print(t.render(ctx))
+	}
+	draw()
+	return keyboard.Listen(func(key keys.Key) (bool, error) {
+		if t.handle(ctx, key) || ctx.Err() != nil {
+			return true, nil
+		}
+		draw()
+		return false, nil
+	})
+}
@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"atomicgo.dev/keyboard/keys"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testTUI(t *testing.T, objects ...runtime.Object) *lensTUI {
+	t.Helper()
+	sf, _ := newTestPlugin(objects...)
+	ctx := context.Background()
+	if err := sf.findPodByName(ctx, "web-7d4b9-abcde", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	tui, err := newLensTUI(ctx, sf, time.Second, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tui
+}
+
+func press(tui *lensTUI, names ...string) {
+	codes := map[string]keys.KeyCode{"enter": keys.Enter, "tab": keys.Tab, "down": keys.Down, "pgdown": keys.PgDown}
+	for _, name := range names {
+		key := keys.Key{Code: keys.RuneKey, Runes: []rune(name)}
+		if code, ok := codes[name]; ok {
+			key = keys.Key{Code: code}
+		}
+		tui.handle(context.Background(), key)
+	}
+}
+
+func selectItem(t *testing.T, tui *lensTUI, kind, name string) {
+	t.Helper()
+	for i, item := range tui.items {
+		if item.ref.Kind == kind && item.ref.Name == name {
+			tui.cursor = i
+			return
+		}
+	}
+	t.Fatalf("%s/%s not in the tree: %v", kind, name, tui.items)
+}
+
+func TestTUIItems(t *testing.T) {
+	sibling := workloadPod("web-7d4b9-fghij", metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-7d4b9"}, map[string]string{"app": "web"})
+	other := workloadPod("api-5c6d7-klmno", metav1.OwnerReference{Kind: "ReplicaSet", Name: "api-5c6d7"}, nil)
+	tui := testTUI(t, append(testCluster(), sibling, other)...)
+
+	var got []string
+	for _, item := range tui.items {
+		got = append(got, strings.Repeat("  ", item.depth)+item.ref.Kind+"/"+item.ref.Name+" "+item.rel)
+	}
+	for _, want := range []string{
+		"Pod/web-7d4b9-abcde ",
+		"  Deployment/web manages",
+		"    Pod/web-7d4b9-fghij manages",
+		"  Node/node-1 scheduled-on",
+		"  Service/web selects",
+		"  ConfigMap/web-config mounts",
+	} {
+		found := false
+		for _, line := range got {
+			found = found || line == want
+		}
+		if !found {
+			t.Errorf("missing %q in tree:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+	for _, line := range got {
+		if strings.Contains(line, "api-5c6d7") {
+			t.Errorf("pod of another workload in the tree: %q", line)
+		}
+	}
+
+	selectItem(t, tui, "Pod", "web-7d4b9-fghij")
+	press(tui, "enter")
+	if root := tui.items[0].ref; root.Name != "web-7d4b9-fghij" || tui.cursor != 0 {
+		t.Fatalf("enter on a sibling opened %v at %d", root, tui.cursor)
+	}
+	selectItem(t, tui, "Pod", "web-7d4b9-abcde")
+	press(tui, "b")
+	if root := tui.items[0].ref; root.Name != "web-7d4b9-abcde" || tui.selected().Name != "web-7d4b9-fghij" {
+		t.Errorf("back returned to %v with %v selected", root, tui.selected())
+	}
+	want := tui.cursor + 2
+	press(tui, "jjb")
+	if tui.status == "" || tui.cursor != want {
+		t.Errorf("typed keys moved to %d, want %d, status %q", tui.cursor, want, tui.status)
+	}
+}
+
+func TestTUIPanes(t *testing.T) {
+	objects := append(testCluster(), &v1.Event{
+		ObjectMeta:     objectMeta("web.1", nil),
+		InvolvedObject: v1.ObjectReference{Kind: "Service", Namespace: testNamespace, Name: "web"},
+		Type:           v1.EventTypeWarning, Reason: "SyncLoadBalancerFailed", Message: "no quota", Count: 2,
+	})
+	objects[len(objects)-2].(*v1.Secret).Annotations = map[string]string{lastAppliedAnnotation: `{"data":{"tls.key":"c2VjcmV0"}}`}
+	tui := testTUI(t, objects...)
+	ctx := context.Background()
+	pane := func() string { return strings.Join(tui.content(ctx), "\n") }
+
+	if got := pane(); !strings.Contains(got, "Status:          Running") || !strings.Contains(got, "app  nginx") {
+		t.Errorf("pod details:\n%s", got)
+	}
+	press(tui, "l")
+	if got := pane(); !strings.Contains(got, "==> app <==") || !strings.Contains(got, "fake logs") {
+		t.Errorf("pod logs:\n%s", got)
+	}
+
+	selectItem(t, tui, "Service", "web")
+	press(tui, "e")
+	if got := pane(); !strings.Contains(got, "SyncLoadBalancerFailed") || !strings.Contains(got, "x2") {
+		t.Errorf("service events:\n%s", got)
+	}
+	press(tui, "d")
+	if got := pane(); !strings.Contains(got, "Kind:            Service") || !strings.Contains(got, "no quota") {
+		t.Errorf("describe without kubectl:\n%s", got)
+	}
+	tui.describe = func(_ context.Context, ref ObjectRef) (string, error) { return "described " + ref.String(), nil }
+	selectItem(t, tui, "Node", "node-1")
+	if got := pane(); got != "described Node/node-1" {
+		t.Errorf("describe = %q", got)
+	}
+	press(tui, "l")
+	if got := pane(); !strings.Contains(got, "for pods") {
+		t.Errorf("logs of a node = %q", got)
+	}
+
+	selectItem(t, tui, "Secret", "web-tls")
+	press(tui, "y")
+	got := pane()
+	if strings.Contains(got, "c2VjcmV0") || strings.Contains(got, "secret\n") || !strings.Contains(got, "tls.key: <redacted>") {
+		t.Errorf("secret YAML leaks or misses the keys:\n%s", got)
+	}
+	if !strings.Contains(got, "kind: Secret") || strings.Contains(got, lastAppliedAnnotation) {
+		t.Errorf("secret YAML:\n%s", got)
+	}
+	press(tui, "tab")
+	if tui.pane != paneEvents {
+		t.Errorf("tab from YAML = %s", paneNames[tui.pane])
+	}
+
+	tui.width, tui.height = 100, 12
+	frame := tui.render(ctx)
+	for _, want := range []string{"Pod/default/web-7d4b9-abcde", "Secret/web-tls", " Events ", "q quit"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame misses %q:\n%s", want, frame)
+		}
+	}
+	if lines := strings.Count(frame, "\r\n"); lines != tui.height-1 {
+		t.Errorf("frame has %d lines, want %d", lines+1, tui.height)
+	}
+	if quit := tui.handle(ctx, keys.Key{Code: keys.RuneKey, Runes: []rune("q")}); !quit {
+		t.Error("q should quit")
+	}
+}
+
+func TestObjectYAMLRedactsTemplates(t *testing.T) {
+	sf, _ := newTestPlugin()
+	sf.redact = []*regexp.Regexp{regexp.MustCompile("PASSWORD")}
+	template := v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app",
+		Env: []v1.EnvVar{{Name: "DB_PASSWORD", Value: "hunter2"}, {Name: "LOG_LEVEL", Value: "info"}}}}}}
+	meta := func(name string) metav1.ObjectMeta {
+		m := objectMeta(name, nil)
+		m.Annotations = map[string]string{lastAppliedAnnotation: `{"value":"hunter2"}`}
+		return m
+	}
+	tests := []runtime.Object{
+		&v1.Pod{ObjectMeta: meta("web-7d4b9-abcde"), Spec: template.Spec},
+		&appsv1.Deployment{ObjectMeta: meta("web"), Spec: appsv1.DeploymentSpec{Template: template}},
+		&appsv1.StatefulSet{ObjectMeta: meta("db"), Spec: appsv1.StatefulSetSpec{Template: template}},
+		&appsv1.DaemonSet{ObjectMeta: meta("agent"), Spec: appsv1.DaemonSetSpec{Template: template}},
+		&appsv1.ReplicaSet{ObjectMeta: meta("web-7d4b9"), Spec: appsv1.ReplicaSetSpec{Template: template}},
+		&batchv1.Job{ObjectMeta: meta("migrate"), Spec: batchv1.JobSpec{Template: template}},
+		&batchv1.CronJob{ObjectMeta: meta("backup"), Spec: batchv1.CronJobSpec{
+			JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}}}},
+		&v1.Service{ObjectMeta: meta("web")},
+	}
+	for _, obj := range tests {
+		got, err := sf.objectYAML(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(got, "hunter2") || strings.Contains(got, lastAppliedAnnotation) {
+			t.Errorf("YAML leaks a value:\n%s", got)
+		}
+		if _, ok := obj.(*v1.Service); !ok && (!strings.Contains(got, "value: <redacted>") || !strings.Contains(got, "value: info")) {
+			t.Errorf("YAML misses the env:\n%s", got)
+		}
+	}
+	if template.Spec.Containers[0].Env[0].Value != "hunter2" {
+		t.Error("objectYAML should not modify the objects it renders")
+	}
+}
+
+func TestTUIOffline(t *testing.T) {
+	tui := testTUI(t, testCluster()...)
+	tui.logs = false
+	press(tui, "l")
+	if got := strings.Join(tui.content(context.Background()), "\n"); !strings.Contains(got, "need a cluster") {
+		t.Errorf("offline logs = %q", got)
+	}
+}
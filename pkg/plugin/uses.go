@@ -0,0 +1,282 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const usageDocumentKind = "PodLensUsage"
+
+var usedKinds = map[string]string{
+	"cm":                     "ConfigMap",
+	"configmap":              "ConfigMap",
+	"configmaps":             "ConfigMap",
+	"secret":                 "Secret",
+	"secrets":                "Secret",
+	"pvc":                    "PersistentVolumeClaim",
+	"persistentvolumeclaim":  "PersistentVolumeClaim",
+	"persistentvolumeclaims": "PersistentVolumeClaim",
+}
+
+// Consumer is a workload, or a pod without one, that uses the object.
+type Consumer struct {
+	Owner  ObjectRef   `json:"owner"`
+	Pods   []string    `json:"pods"`
+	Usages []Reference `json:"usages"`
+}
+
+// UsageDocument is the machine-readable form of a reverse lookup.
+type UsageDocument struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Target     ObjectRef    `json:"target"`
+	Missing    bool         `json:"missing,omitempty"`
+	Consumers  []Consumer   `json:"consumers"`
+	NotVisible []NotVisible `json:"notVisible,omitempty"`
+}
+
+func parseUsesTarget(arg string) (kind, name string, err error) {
+	parts := strings.SplitN(arg, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", errors.Errorf("expected kind/name, e.g. secret/db-creds, got %q", arg)
+	}
+	kind, ok := usedKinds[strings.ToLower(parts[0])]
+	if !ok {
+		return "", "", errors.Errorf("unsupported kind %q in %q, expected one of configmap, secret or pvc", parts[0], arg)
+	}
+	return kind, parts[1], nil
+}
+
+// findConsumers scans the pods in the namespace, or all namespaces when it is
+// empty, for references to the object and groups them by owning workload.
+func (sf *SnifferPlugin) findConsumers(ctx context.Context, kind, name, namespace string) ([]Consumer, error) {
+	pods, err := sf.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+	owners, err := sf.workloadOwners(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var consumers []*Consumer
+	index := make(map[ObjectRef]*Consumer)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		var refs []Reference
+		for _, ref := range podReferences(pod) {
+			if ref.Kind == kind && ref.Name == name {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		owner := podOwner(pod, owners)
+		c, ok := index[owner]
+		if !ok {
+			c = &Consumer{Owner: owner}
+			index[owner] = c
+			consumers = append(consumers, c)
+		}
+		c.Pods = append(c.Pods, pod.Name)
+		// Pods of one workload share their spec, so each usage is kept once.
+		for _, ref := range refs {
+			seen := false
+			for _, u := range c.Usages {
+				if usageKey(u) == usageKey(ref) {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				c.Usages = append(c.Usages, ref)
+			}
+		}
+	}
+
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Owner.String() < consumers[j].Owner.String() })
+	result := make([]Consumer, 0, len(consumers))
+	for _, c := range consumers {
+		sort.Strings(c.Pods)
+		result = append(result, *c)
+	}
+	return result, nil
+}
+
+func usageKey(r Reference) string {
+	return r.Via + "|" + r.Usage()
+}
+
+// workloadOwners maps ReplicaSets and Jobs to the Deployment or CronJob that
+// owns them. When they cannot be listed pods are grouped by their direct owner.
+func (sf *SnifferPlugin) workloadOwners(ctx context.Context, namespace string) (map[ObjectRef]ObjectRef, error) {
+	owners := make(map[ObjectRef]ObjectRef)
+	add := func(kind string, meta metav1.ObjectMeta) {
+		for _, own := range meta.OwnerReferences {
+			if own.Controller == nil || *own.Controller {
+				owners[ObjectRef{Kind: kind, Namespace: meta.Namespace, Name: meta.Name}] =
+					ObjectRef{Kind: own.Kind, Namespace: meta.Namespace, Name: own.Name}
+			}
+		}
+	}
+	rsList, err := sf.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if !sf.notVisible("replicasets", err) {
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list replica sets")
+		}
+		for _, rs := range rsList.Items {
+			add("ReplicaSet", rs.ObjectMeta)
+		}
+	}
+	jobList, err := sf.Clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if !sf.notVisible("jobs", err) {
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list jobs")
+		}
+		for _, job := range jobList.Items {
+			add("Job", job.ObjectMeta)
+		}
+	}
+	return owners, nil
+}
+
+func podOwner(pod *v1.Pod, owners map[ObjectRef]ObjectRef) ObjectRef {
+	for _, own := range pod.OwnerReferences {
+		if own.Controller != nil && !*own.Controller {
+			continue
+		}
+		ref := ObjectRef{Kind: own.Kind, Namespace: pod.Namespace, Name: own.Name}
+		if parent, ok := owners[ref]; ok {
+			return parent
+		}
+		return ref
+	}
+	return newObjectRef("Pod", pod)
+}
+
+// targetMissing reports whether the object does not exist. It is only checked
+// in a single namespace, a missing object may still have consumers.
+func (sf *SnifferPlugin) targetMissing(ctx context.Context, kind, name, namespace string) (bool, error) {
+	if namespace == "" {
+		return false, nil
+	}
+	var err error
+	switch kind {
+	case "ConfigMap":
+		_, err = sf.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	case "Secret":
+		_, err = sf.Clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	case "PersistentVolumeClaim":
+		_, err = sf.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if sf.notVisible(referenceResources[kind], err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// RunUses lists every pod, grouped by workload, that uses a ConfigMap, Secret or PVC.
+func RunUses(configFlags *genericclioptions.ConfigFlags, target string, opts Options) error {
+	if opts.Output != "" && opts.Output != "json" && opts.Output != "yaml" {
+		return errors.Errorf("unsupported output format %q, expected one of [json yaml]", opts.Output)
+	}
+	kind, name, err := parseUsesTarget(target)
+	if err != nil {
+		return err
+	}
+	sf, err := newPluginForOptions(configFlags, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	timeout, err := requestTimeout(configFlags)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	namespace := lookupNamespace(configFlags, opts)
+	missing, err := sf.targetMissing(ctx, kind, name, namespace)
+	if err != nil {
+		return contextError(ctx, timeout, errors.Wrapf(err, "failed to get %s %s", kind, name))
+	}
+	consumers, err := sf.findConsumers(ctx, kind, name, namespace)
+	if err != nil {
+		return contextError(ctx, timeout, err)
+	}
+
+	doc := &UsageDocument{
+		APIVersion: documentAPIVersion,
+		Kind:       usageDocumentKind,
+		Target:     ObjectRef{Kind: kind, Namespace: namespace, Name: name},
+		Missing:    missing,
+		Consumers:  consumers,
+		NotVisible: sf.AllInfo.NotVisible,
+	}
+	if opts.Output != "" {
+		return writeDocument(os.Stdout, opts.Output, doc)
+	}
+	printUsage(doc)
+	sf.printNotVisible()
+	return nil
+}
+
+func printUsage(doc *UsageDocument) {
+	scope := "namespace " + doc.Target.Namespace
+	if doc.Target.Namespace == "" {
+		scope = "all namespaces"
+	}
+	title := cfmt.Sprintf(referenceStyles[doc.Target.Kind], doc.Target.Name)
+	if doc.Missing {
+		title += cfmt.Sprint(" {{(missing)}}::red|bold")
+	}
+	if len(doc.Consumers) == 0 {
+		fmt.Printf("%s\nNo pods in %s use it.\n", title, scope)
+		return
+	}
+
+	var pods int
+	var leveledList pterm.LeveledList
+	for _, c := range doc.Consumers {
+		pods += len(c.Pods)
+		owner := c.Owner.Name
+		if doc.Target.Namespace == "" {
+			owner = c.Owner.Namespace + "/" + owner
+		}
+		text := cfmt.Sprintf("{{ [%s] }}::lightBlue|bold %s", c.Owner.Kind, owner)
+		if c.Owner.Kind != "Pod" {
+			text += pterm.Gray(fmt.Sprintf(" (%d pods: %s)", len(c.Pods), strings.Join(c.Pods, ", ")))
+		}
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 0, Text: text})
+		for _, u := range c.Usages {
+			leveledList = append(leveledList, pterm.LeveledListItem{Level: 1, Text: cfmt.Sprintf("{{%s}}::gray", u.Usage())})
+		}
+	}
+	root := pterm.NewTreeFromLeveledList(leveledList)
+	root.Text = title
+	_ = pterm.DefaultTree.WithRoot(root).Render()
+	fmt.Printf("Used by %d pod(s) in %d workload(s) in %s.\n", pods, len(doc.Consumers), scope)
+}
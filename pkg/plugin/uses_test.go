@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseUsesTarget(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantKind string
+		wantErr  bool
+	}{
+		{arg: "secret/db-creds", wantKind: "Secret"},
+		{arg: "cm/web-config", wantKind: "ConfigMap"},
+		{arg: "PVC/data", wantKind: "PersistentVolumeClaim"},
+		{arg: "svc/web", wantErr: true},
+		{arg: "db-creds", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			kind, _, err := parseUsesTarget(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUsesTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if kind != tt.wantKind {
+				t.Errorf("parseUsesTarget() kind = %q, want %q", kind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestFindConsumers(t *testing.T) {
+	secretEnv := func(pod *v1.Pod) *v1.Pod {
+		pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "db-creds"}, Key: "password"}}}}
+		return pod
+	}
+	rs := metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-7d4b9"}
+	web1 := secretEnv(workloadPod("web-1", rs, nil))
+	web2 := secretEnv(workloadPod("web-2", rs, nil))
+	bare := testPod()
+	bare.Name, bare.OwnerReferences = "debug", nil
+	bare.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: "db-creds"}}
+	elsewhere := secretEnv(workloadPod("api-1", metav1.OwnerReference{Kind: "StatefulSet", Name: "api"}, nil))
+	elsewhere.Namespace = "staging"
+	unrelated := workloadPod("db-0", metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, nil)
+	objects := []runtime.Object{
+		web1, web2, bare, elsewhere, unrelated,
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-7d4b9", Namespace: testNamespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web"}}}},
+	}
+	envUsage := Reference{Kind: "Secret", Name: "db-creds", Via: ViaEnv, Container: "app", EnvVar: "PASSWORD", Keys: []string{"password"}}
+	pullUsage := Reference{Kind: "Secret", Name: "db-creds", Via: ViaImagePullSecret}
+
+	tests := []struct {
+		name      string
+		namespace string
+		forbidden string
+		want      []Consumer
+	}{
+		{
+			name:      "grouped by workload",
+			namespace: testNamespace,
+			want: []Consumer{
+				{Owner: ObjectRef{Kind: "Deployment", Namespace: testNamespace, Name: "web"}, Pods: []string{"web-1", "web-2"}, Usages: []Reference{envUsage}},
+				{Owner: ObjectRef{Kind: "Pod", Namespace: testNamespace, Name: "debug"}, Pods: []string{"debug"}, Usages: []Reference{pullUsage}},
+			},
+		},
+		{
+			name: "all namespaces",
+			want: []Consumer{
+				{Owner: ObjectRef{Kind: "Deployment", Namespace: testNamespace, Name: "web"}, Pods: []string{"web-1", "web-2"}, Usages: []Reference{envUsage}},
+				{Owner: ObjectRef{Kind: "Pod", Namespace: testNamespace, Name: "debug"}, Pods: []string{"debug"}, Usages: []Reference{pullUsage}},
+				{Owner: ObjectRef{Kind: "StatefulSet", Namespace: "staging", Name: "api"}, Pods: []string{"api-1"}, Usages: []Reference{envUsage}},
+			},
+		},
+		{
+			name:      "replica sets not visible",
+			namespace: testNamespace,
+			forbidden: "replicasets",
+			want: []Consumer{
+				{Owner: ObjectRef{Kind: "Pod", Namespace: testNamespace, Name: "debug"}, Pods: []string{"debug"}, Usages: []Reference{pullUsage}},
+				{Owner: ObjectRef{Kind: "ReplicaSet", Namespace: testNamespace, Name: "web-7d4b9"}, Pods: []string{"web-1", "web-2"}, Usages: []Reference{envUsage}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, clientset := newTestPlugin(objects...)
+			if tt.forbidden != "" {
+				forbid(clientset, "list", tt.forbidden)
+			}
+			got, err := sf.findConsumers(context.Background(), "Secret", "db-creds", tt.namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findConsumers() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetMissing(t *testing.T) {
+	sf, _ := newTestPlugin(&v1.Secret{ObjectMeta: objectMeta("db-creds", nil)})
+	ctx := context.Background()
+	if missing, err := sf.targetMissing(ctx, "Secret", "db-creds", testNamespace); err != nil || missing {
+		t.Errorf("existing secret: missing = %v, err = %v", missing, err)
+	}
+	if missing, err := sf.targetMissing(ctx, "Secret", "gone", testNamespace); err != nil || !missing {
+		t.Errorf("missing secret: missing = %v, err = %v", missing, err)
+	}
+	if missing, err := sf.targetMissing(ctx, "ConfigMap", "gone", ""); err != nil || missing {
+		t.Errorf("all namespaces: missing = %v, err = %v", missing, err)
+	}
+}
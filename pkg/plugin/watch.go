@@ -0,0 +1,319 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchDebounce lets the burst of events of a rollout settle before a redraw.
+const watchDebounce = 300 * time.Millisecond
+
+// watchedResources are the namespaced kinds --watch keeps informers on.
+var watchedResources = map[string]schema.GroupVersionResource{
+	"pods":                     {Version: "v1", Resource: "pods"},
+	"replicasets":              {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"deployments":              {Group: "apps", Version: "v1", Resource: "deployments"},
+	"statefulsets":             {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"daemonsets":               {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"jobs":                     {Group: "batch", Version: "v1", Resource: "jobs"},
+	"cronjobs":                 {Group: "batch", Version: "v1", Resource: "cronjobs"},
+	"services":                 {Version: "v1", Resource: "services"},
+	"ingresses":                {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"persistentvolumeclaims":   {Version: "v1", Resource: "persistentvolumeclaims"},
+	"configmaps":               {Version: "v1", Resource: "configmaps"},
+	"secrets":                  {Version: "v1", Resource: "secrets"},
+	"serviceaccounts":          {Version: "v1", Resource: "serviceaccounts"},
+	"horizontalpodautoscalers": {Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"},
+	"poddisruptionbudgets":     {Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+}
+
+var changeStyle = pterm.NewStyle(pterm.BgYellow, pterm.FgBlack)
+
+// was records the value shown for key in the current --watch frame and, when
+// it differs from the previous frame, returns a note with the old value.
+func (sf *SnifferPlugin) was(key, value string) string {
+	if sf.current == nil {
+		return ""
+	}
+	sf.current[key] = value
+	old, ok := sf.previous[key]
+	if !ok || old == value {
+		return ""
+	}
+	return " " + changeStyle.Sprint(" was "+old+" ")
+}
+
+// added returns a note when key was not part of the previous --watch frame.
+func (sf *SnifferPlugin) added(key string) string {
+	if sf.current == nil {
+		return ""
+	}
+	sf.current[key] = ""
+	if _, ok := sf.previous[key]; ok || sf.previous == nil {
+		return ""
+	}
+	return " " + changeStyle.Sprint(" new ")
+}
+
+// watchNeeded reports whether frames need the resource. Owners are always
+// looked up, and the referenced objects also when only references are enabled.
+func (sf *SnifferPlugin) watchNeeded(resource string) bool {
+	switch resource {
+	case "pods", "replicasets", "deployments", "statefulsets", "daemonsets", "jobs", "cronjobs":
+		return true
+	case "configmaps", "secrets", "persistentvolumeclaims":
+		return sf.enabled(resource) || sf.enabled("references")
+	case "serviceaccounts":
+		return sf.enabled("references")
+	}
+	return sf.enabled(resource)
+}
+
+type podWatch struct {
+	kind      string
+	name      string
+	namespace string
+	nodeName  string
+	opts      Options
+	resources map[string]bool
+	stores    []cache.Store
+	nodes     cache.Store
+	hidden    map[string]error
+	changed   chan struct{}
+	previous  map[string]string
+}
+
+func (w *podWatch) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// newPodWatch starts informers on the namespace of the selected pod or
+// workload, and on the pod's node, and waits for them to sync.
+func (sf *SnifferPlugin) newPodWatch(ctx context.Context, timeout time.Duration, kind string, opts Options) (*podWatch, error) {
+	w := &podWatch{
+		kind:      kind,
+		name:      sf.PodObject.Name,
+		namespace: sf.PodObject.Namespace,
+		opts:      opts,
+		resources: sf.withoutEvents(),
+		hidden:    make(map[string]error),
+		changed:   make(chan struct{}, 1),
+	}
+	if !sf.fromWorkload {
+		w.nodeName = sf.PodObject.Spec.NodeName
+	}
+	syncCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		syncCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// An informer on a kind the user cannot list would never sync, so each
+	// kind is probed first.
+	var mu sync.Mutex
+	hide := func(resource string, err error) bool {
+		if !sf.notVisible(resource, err) {
+			return false
+		}
+		mu.Lock()
+		w.hidden[resource] = err
+		mu.Unlock()
+		return true
+	}
+	var tasks []task
+	for resource := range watchedResources {
+		if !sf.watchNeeded(resource) {
+			continue
+		}
+		resource := resource
+		tasks = append(tasks, func(ctx context.Context) error {
+			_, err := namespaceLists[resource](ctx, sf.Clientset, w.namespace, metav1.ListOptions{Limit: 1})
+			if hide(resource, err) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to list %s", resource)
+		})
+	}
+	if !sf.fromWorkload {
+		tasks = append(tasks, func(ctx context.Context) error {
+			var err error
+			if w.nodeName != "" {
+				_, err = sf.Clientset.CoreV1().Nodes().Get(ctx, w.nodeName, metav1.GetOptions{})
+			} else {
+				_, err = sf.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
+			}
+			if hide("nodes", err) {
+				return nil
+			}
+			return errors.Wrap(err, "failed to get nodes")
+		})
+	}
+	if err := runConcurrently(syncCtx, tasks...); err != nil {
+		return nil, contextError(syncCtx, timeout, err)
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { w.notify() },
+		UpdateFunc: func(interface{}, interface{}) { w.notify() },
+		DeleteFunc: func(interface{}) { w.notify() },
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(sf.Clientset, 0, informers.WithNamespace(w.namespace))
+	for resource, gvr := range watchedResources {
+		if _, hidden := w.hidden[resource]; hidden || !sf.watchNeeded(resource) {
+			continue
+		}
+		informer, err := factory.ForResource(gvr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = informer.Informer().AddEventHandler(handler); err != nil {
+			return nil, err
+		}
+		w.stores = append(w.stores, informer.Informer().GetStore())
+	}
+	factory.Start(ctx.Done())
+	synced := factory.WaitForCacheSync(syncCtx.Done())
+
+	if _, hidden := w.hidden["nodes"]; !hidden && !sf.fromWorkload {
+		// A pending pod may land on any node, a scheduled one stays on its node.
+		nodeFactory := informers.NewSharedInformerFactoryWithOptions(sf.Clientset, 0,
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				if w.nodeName != "" {
+					o.FieldSelector = fields.OneTermEqualSelector("metadata.name", w.nodeName).String()
+				}
+			}))
+		nodeInformer := nodeFactory.Core().V1().Nodes().Informer()
+		if _, err := nodeInformer.AddEventHandler(handler); err != nil {
+			return nil, err
+		}
+		w.nodes = nodeInformer.GetStore()
+		nodeFactory.Start(ctx.Done())
+		for t, ok := range nodeFactory.WaitForCacheSync(syncCtx.Done()) {
+			synced[t] = ok
+		}
+	}
+	for _, ok := range synced {
+		if !ok {
+			return nil, contextError(syncCtx, timeout, errors.New("failed to sync the watch caches"))
+		}
+	}
+	return w, nil
+}
+
+// frame runs the lookups of the pod or workload against the informer caches.
+func (w *podWatch) frame(ctx context.Context) (*SnifferPlugin, error) {
+	var objects []runtime.Object
+	for _, store := range w.stores {
+		for _, obj := range store.List() {
+			objects = append(objects, obj.(runtime.Object))
+		}
+	}
+	frame := NewSnifferPluginForClients(newSnapshotClientset(objects, w.hidden), nil)
+	frame.resources = w.resources
+	frame.graphOnly = true
+	frame.previous, frame.current = w.previous, make(map[string]string)
+
+	if w.kind != "" {
+		if err := frame.findWorkload(ctx, w.kind, w.name, w.namespace); err != nil {
+			return nil, err
+		}
+	} else {
+		pod, err := frame.Clientset.CoreV1().Pods(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("pod %s/%s no longer exists", w.namespace, w.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		frame.PodObject = pod
+	}
+	if err := frame.getLabelByPod(w.opts.LabelSelector, w.opts.LabelKeys); err != nil {
+		return nil, err
+	}
+	if err := frame.findRelated(ctx, w.opts); err != nil {
+		return nil, err
+	}
+	if !frame.fromWorkload {
+		if w.nodes != nil && frame.PodObject.Spec.NodeName != "" {
+			if obj, ok, _ := w.nodes.GetByKey(frame.PodObject.Spec.NodeName); ok {
+				frame.AllInfo.Node = obj.(*v1.Node)
+			}
+		}
+		frame.AllInfo.Diagnostics = frame.diagnoseContainers()
+	}
+	return frame, nil
+}
+
+// runWatch redraws the pod tree in place whenever the pod, its owner, its
+// node or a related object changes, marking the values that changed.
+func (sf *SnifferPlugin) runWatch(ctx context.Context, timeout time.Duration, kind string, opts Options) error {
+	w, err := sf.newPodWatch(ctx, timeout, kind, opts)
+	if err != nil {
+		return err
+	}
+	sf.printNotVisible()
+	target := "pod/" + w.name
+	if kind != "" {
+		target = strings.ToLower(kind) + "/" + w.name
+	}
+
+	area, err := pterm.DefaultArea.Start()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = area.Stop() }()
+	render := func() {
+		var content string
+		frame, err := w.frame(ctx)
+		if err != nil {
+			content = pterm.Red(err.Error()) + "\n"
+		} else {
+			content = frame.podPanel()
+			w.previous = frame.current
+		}
+		header := fmt.Sprintf("Watching %s in %s, updated %s, press Ctrl+C to stop",
+			target, w.namespace, time.Now().Format("15:04:05"))
+		area.Update(pterm.Gray(header) + "\n" + content)
+	}
+
+	select {
+	case <-w.changed:
+	default:
+	}
+	render()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.changed:
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchDebounce):
+		}
+		select {
+		case <-w.changed:
+		default:
+		}
+		render()
+	}
+}
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWas(t *testing.T) {
+	sf := &SnifferPlugin{}
+	if got := sf.was("phase", "Running"); got != "" {
+		t.Errorf("outside --watch was() = %q", got)
+	}
+
+	sf.current = make(map[string]string)
+	if got := sf.was("phase", "Running") + sf.added("pod/web-1"); got != "" {
+		t.Errorf("first frame = %q, want no notes", got)
+	}
+	sf.previous, sf.current = sf.current, make(map[string]string)
+	if got := sf.was("phase", "Running"); got != "" {
+		t.Errorf("unchanged value = %q", got)
+	}
+	if got := sf.was("restarts", "3"); got != "" {
+		t.Errorf("value without a previous frame entry = %q", got)
+	}
+	sf.previous, sf.current = sf.current, make(map[string]string)
+	if got := sf.was("restarts", "4"); !strings.Contains(got, " was 3 ") {
+		t.Errorf("changed value = %q, want the old value", got)
+	}
+	if got := sf.added("pod/web-2"); !strings.Contains(got, " new ") {
+		t.Errorf("added() = %q, want a note", got)
+	}
+	if want := map[string]string{"restarts": "4", "pod/web-2": ""}; !reflect.DeepEqual(sf.current, want) {
+		t.Errorf("current = %v, want %v", sf.current, want)
+	}
+}
+
+func waitChanged(t *testing.T, w *podWatch) {
+	t.Helper()
+	select {
+	case <-w.changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notified")
+	}
+}
+
+func TestPodWatch(t *testing.T) {
+	sf, clientset := newTestPlugin(testCluster()...)
+	forbid(clientset, "list", "secrets")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := sf.findPodByName(ctx, "web-7d4b9-abcde", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := sf.newPodWatch(ctx, 5*time.Second, "", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.hidden["secrets"]; !ok || len(w.hidden) != 1 {
+		t.Errorf("hidden = %v, want secrets", w.hidden)
+	}
+	frame, err := w.frame(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.AllInfo.Node == nil || frame.AllInfo.Node.Name != "node-1" || frame.AllInfo.Workload.Name != "web" {
+		t.Errorf("frame node = %v, workload = %+v", frame.AllInfo.Node, frame.AllInfo.Workload)
+	}
+	if panel := frame.podPanel(); strings.Contains(panel, " was ") {
+		t.Errorf("first frame marks changes:\n%s", panel)
+	}
+	w.previous = frame.current
+
+	select {
+	case <-w.changed:
+	default:
+	}
+	pod := testPod()
+	pod.Status.Phase = v1.PodFailed
+	if _, err = clientset.CoreV1().Pods(testNamespace).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitChanged(t, w)
+	if frame, err = w.frame(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if panel := frame.podPanel(); !strings.Contains(panel, " was Running ") {
+		t.Errorf("phase change not marked:\n%s", panel)
+	}
+
+	if err = clientset.CoreV1().Pods(testNamespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitChanged(t, w)
+	if _, err = w.frame(ctx); err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Errorf("deleted pod frame error = %v", err)
+	}
+}
@@ -0,0 +1,361 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// workloadAliases maps the kind part of a `kind/name` argument, with or
+// without its API group, to the workload kind. Nodes and namespaces get a lens
+// of their own.
+var workloadAliases = map[string]string{
+	"deploy":       "Deployment",
+	"deployment":   "Deployment",
+	"deployments":  "Deployment",
+	"sts":          "StatefulSet",
+	"statefulset":  "StatefulSet",
+	"statefulsets": "StatefulSet",
+	"ds":           "DaemonSet",
+	"daemonset":    "DaemonSet",
+	"daemonsets":   "DaemonSet",
+	"job":          "Job",
+	"jobs":         "Job",
+	"cj":           "CronJob",
+	"cronjob":      "CronJob",
+	"cronjobs":     "CronJob",
+	"no":           "Node",
+	"node":         "Node",
+	"nodes":        "Node",
+	"ns":           "Namespace",
+	"namespace":    "Namespace",
+	"namespaces":   "Namespace",
+}
+
+// PodSummary is the status of one pod of the workload the lens started from.
+type PodSummary struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Ready     string `json:"ready"`
+	Restarts  int32  `json:"restarts"`
+	Node      string `json:"node,omitempty"`
+	PodIP     string `json:"podIP,omitempty"`
+	Unhealthy bool   `json:"unhealthy"`
+}
+
+// parseTarget splits a `kind/name` argument. Plain pod names, `pod/name` and
+// `po/name` return an empty kind.
+func parseTarget(arg string) (kind, name string, err error) {
+	i := strings.Index(arg, "/")
+	if i < 0 {
+		return "", arg, nil
+	}
+	resource, name := strings.ToLower(arg[:i]), arg[i+1:]
+	if name == "" {
+		return "", "", errors.Errorf("missing name in %q", arg)
+	}
+	resource = strings.SplitN(resource, ".", 2)[0]
+	switch resource {
+	case "po", "pod", "pods":
+		return "", name, nil
+	}
+	kind, ok := workloadAliases[resource]
+	if !ok {
+		return "", "", errors.Errorf("unsupported kind %q in %q, expected one of pod, deploy, sts, ds, job, cronjob, node or ns", resource, arg)
+	}
+	return kind, name, nil
+}
+
+type workloadTarget struct {
+	meta     metav1.ObjectMeta
+	selector *metav1.LabelSelector
+	template v1.PodTemplateSpec
+	workload Workload
+}
+
+// findWorkload looks up the workload and its pods. The lens then runs on a pod
+// built from the workload's pod template, so the related resources shared by
+// all pods are found once.
+func (sf *SnifferPlugin) findWorkload(ctx context.Context, kind, name, namespace string) error {
+	opts := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String()}
+	var targets []workloadTarget
+	switch kind {
+	case "Deployment":
+		list, err := sf.Clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "failed to get deployments")
+		}
+		for _, i := range list.Items {
+			targets = append(targets, workloadTarget{meta: i.ObjectMeta, selector: i.Spec.Selector, template: i.Spec.Template,
+				workload: Workload{Replicas: fmt.Sprintf("%d/%d", i.Status.ReadyReplicas, i.Status.Replicas),
+					Status: i.Status.ReadyReplicas != i.Status.Replicas}})
+		}
+	case "StatefulSet":
+		list, err := sf.Clientset.AppsV1().StatefulSets(namespace).List(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "failed to get stateful sets")
+		}
+		for _, i := range list.Items {
+			targets = append(targets, workloadTarget{meta: i.ObjectMeta, selector: i.Spec.Selector, template: i.Spec.Template,
+				workload: Workload{Replicas: fmt.Sprintf("%d/%d", i.Status.ReadyReplicas, i.Status.Replicas),
+					Status: i.Status.ReadyReplicas != i.Status.Replicas}})
+		}
+	case "DaemonSet":
+		list, err := sf.Clientset.AppsV1().DaemonSets(namespace).List(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "failed to get daemon sets")
+		}
+		for _, i := range list.Items {
+			targets = append(targets, workloadTarget{meta: i.ObjectMeta, selector: i.Spec.Selector, template: i.Spec.Template,
+				workload: Workload{Replicas: fmt.Sprintf("%d/%d", i.Status.NumberReady, i.Status.DesiredNumberScheduled),
+					Status: i.Status.NumberReady != i.Status.DesiredNumberScheduled}})
+		}
+	case "Job":
+		list, err := sf.Clientset.BatchV1().Jobs(namespace).List(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "failed to get jobs")
+		}
+		for _, i := range list.Items {
+			completions := int32(1)
+			if i.Spec.Completions != nil {
+				completions = *i.Spec.Completions
+			}
+			targets = append(targets, workloadTarget{meta: i.ObjectMeta, selector: i.Spec.Selector, template: i.Spec.Template,
+				workload: Workload{Replicas: fmt.Sprintf("%d/%d", i.Status.Succeeded, completions),
+					Status: i.Status.Failed > 0}})
+		}
+	case "CronJob":
+		list, err := sf.Clientset.BatchV1().CronJobs(namespace).List(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "failed to get cron jobs")
+		}
+		for _, i := range list.Items {
+			targets = append(targets, workloadTarget{meta: i.ObjectMeta, template: i.Spec.JobTemplate.Spec.Template,
+				workload: Workload{Replicas: fmt.Sprintf("%d active", len(i.Status.Active))}})
+		}
+	}
+
+	var found []workloadTarget
+	for _, t := range targets {
+		if t.meta.Name == name {
+			found = append(found, t)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return errors.Errorf("Failed to get %s: [%s], please check your parameters, set a context or verify API server.", kind, name)
+	case len(found) > 1:
+		var namespaces []string
+		for _, t := range found {
+			namespaces = append(namespaces, t.meta.Namespace)
+		}
+		return errors.Errorf("%s %s exists in namespaces %s, use -n to pick one", kind, name, strings.Join(namespaces, ", "))
+	}
+	target := found[0]
+
+	pods, err := sf.workloadPods(ctx, kind, target)
+	if err != nil {
+		return err
+	}
+	target.workload.Type = kind
+	target.workload.Name = name
+	sf.AllInfo.Workload = target.workload
+	sf.AllInfo.Pods = pods
+	sf.fromWorkload = true
+	sf.PodObject = &v1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   target.meta.Namespace,
+			Labels:      target.template.Labels,
+			Annotations: target.template.Annotations,
+		},
+		Spec: target.template.Spec,
+	}
+	return nil
+}
+
+// workloadPods lists the pods selected by the workload, or for a CronJob the
+// pods of the jobs it owns.
+func (sf *SnifferPlugin) workloadPods(ctx context.Context, kind string, target workloadTarget) ([]v1.Pod, error) {
+	namespace := target.meta.Namespace
+	var pods []v1.Pod
+	if kind == "CronJob" {
+		jobs, err := sf.Clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get jobs")
+		}
+		owned := make(map[string]bool)
+		for _, job := range jobs.Items {
+			if isOwnedBy(job.ObjectMeta, "CronJob", target.meta.Name) {
+				owned[job.Name] = true
+			}
+		}
+		podList, err := sf.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get pods")
+		}
+		for _, pod := range podList.Items {
+			for _, own := range pod.OwnerReferences {
+				if own.Kind == "Job" && owned[own.Name] {
+					pods = append(pods, pod)
+					break
+				}
+			}
+		}
+	} else {
+		selector := labels.SelectorFromSet(target.template.Labels)
+		if target.selector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(target.selector); err != nil {
+				return nil, err
+			}
+		}
+		if selector.Empty() {
+			return nil, nil
+		}
+		podList, err := sf.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get pods")
+		}
+		pods = podList.Items
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
+
+func isOwnedBy(meta metav1.ObjectMeta, kind, name string) bool {
+	for _, own := range meta.OwnerReferences {
+		if own.Kind == kind && own.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func summarizePod(pod *v1.Pod) PodSummary {
+	s := PodSummary{
+		Name:  pod.Name,
+		State: string(pod.Status.Phase),
+		Node:  pod.Spec.NodeName,
+		PodIP: pod.Status.PodIP,
+	}
+	if s.State == "" {
+		s.State = string(v1.PodPending)
+	}
+	var ready int
+	for _, c := range pod.Status.ContainerStatuses {
+		if c.Ready {
+			ready++
+		}
+		s.Restarts += c.RestartCount
+		if c.State.Waiting != nil && c.State.Waiting.Reason != "" {
+			s.State = c.State.Waiting.Reason
+		} else if c.State.Terminated != nil && c.State.Terminated.Reason != "Completed" && pod.Status.Phase == v1.PodRunning {
+			s.State = c.State.Terminated.Reason
+		}
+	}
+	if pod.DeletionTimestamp != nil {
+		s.State = "Terminating"
+	}
+	s.Ready = fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers))
+	switch s.State {
+	case string(v1.PodRunning):
+		s.Unhealthy = ready != len(pod.Spec.Containers)
+	case string(v1.PodSucceeded):
+	default:
+		s.Unhealthy = true
+	}
+	return s
+}
+
+func (sf *SnifferPlugin) podSummaries() []PodSummary {
+	summaries := []PodSummary{}
+	for i := range sf.AllInfo.Pods {
+		summaries = append(summaries, summarizePod(&sf.AllInfo.Pods[i]))
+	}
+	return summaries
+}
+
+// podStateCounts summarizes the pod states, e.g. "2 Running, 1 CrashLoopBackOff".
+func podStateCounts(summaries []PodSummary) string {
+	counts := make(map[string]int)
+	var states []string
+	for _, s := range summaries {
+		if counts[s.State] == 0 {
+			states = append(states, s.State)
+		}
+		counts[s.State]++
+	}
+	sort.Strings(states)
+	var parts []string
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[state], state))
+	}
+	if len(parts) == 0 {
+		return "no pods"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (sf *SnifferPlugin) workloadPanel() string {
+	var leveledList pterm.LeveledList
+	var stateList string
+	leveledList = append(leveledList, pterm.LeveledListItem{Level: 0,
+		Text: cfmt.Sprintf("{{ [Namespace] }}::cyan|bold %s", sf.PodObject.Namespace)})
+	if sf.LabelSelector != "" {
+		stateList += "Selector: " + pterm.Cyan(sf.LabelSelector)
+	}
+	stateList += "\n"
+
+	summaries := sf.podSummaries()
+	replicas := pterm.Green(sf.AllInfo.Workload.Replicas)
+	if sf.AllInfo.Workload.Status {
+		replicas = pterm.Red(sf.AllInfo.Workload.Replicas)
+	}
+	leveledList = append(leveledList, pterm.LeveledListItem{Level: 1,
+		Text: cfmt.Sprintf("{{ [%s] }}::lightBlue|bold %s", sf.AllInfo.Workload.Type, sf.AllInfo.Workload.Name)})
+	stateList += fmt.Sprintf("Replica: %s%s Pods: %s\n", replicas,
+		sf.was("replica", sf.AllInfo.Workload.Replicas), podStateCounts(summaries))
+
+	for _, s := range summaries {
+		leveledList = append(leveledList, pterm.LeveledListItem{Level: 2,
+			Text: cfmt.Sprintf("{{ [Pod] }}::blue|bold %s", s.Name) + sf.added("pod/"+s.Name)})
+		state := pterm.Green("[" + s.State + "]")
+		if s.Unhealthy {
+			state = pterm.Red("[" + s.State + "]")
+		}
+		restarts := pterm.Green(s.Restarts)
+		if s.Restarts != 0 {
+			restarts = pterm.Yellow(s.Restarts)
+		}
+		node := s.Node
+		if node == "" {
+			node = pterm.Red("<not scheduled>")
+		}
+		key := "pod/" + s.Name + "/"
+		stateList += fmt.Sprintf("%s%s Ready: %s%s Restart: %s%s Node: %s\n",
+			pterm.Bold.Sprint(state), sf.was(key+"state", s.State),
+			s.Ready, sf.was(key+"ready", s.Ready),
+			restarts, sf.was(key+"restarts", fmt.Sprint(s.Restarts)), node)
+	}
+	leveledList = append(leveledList, sf.referenceLeveledList()...)
+	leveledList = append(leveledList, sf.trafficLeveledList()...)
+	root := pterm.NewTreeFromLeveledList(leveledList)
+	tree, _ := pterm.DefaultTree.WithRoot(root).Srender()
+
+	panels := pterm.Panels{
+		{{Data: tree}, {Data: stateList}},
+	}
+	panel, _ := pterm.DefaultPanel.WithPanels(panels).WithPadding(5).Srender()
+	return panel
+}
@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantKind string
+		wantName string
+		wantErr  bool
+	}{
+		{arg: "web-7d4b9-abcde", wantName: "web-7d4b9-abcde"},
+		{arg: "pod/web-0", wantName: "web-0"},
+		{arg: "deploy/web", wantKind: "Deployment", wantName: "web"},
+		{arg: "deployments.apps/web", wantKind: "Deployment", wantName: "web"},
+		{arg: "STS/db", wantKind: "StatefulSet", wantName: "db"},
+		{arg: "ds/agent", wantKind: "DaemonSet", wantName: "agent"},
+		{arg: "job/migrate", wantKind: "Job", wantName: "migrate"},
+		{arg: "cj/backup", wantKind: "CronJob", wantName: "backup"},
+		{arg: "node/node-1", wantKind: "Node", wantName: "node-1"},
+		{arg: "ns/default", wantKind: "Namespace", wantName: "default"},
+		{arg: "svc/web", wantErr: true},
+		{arg: "deploy/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			kind, name, err := parseTarget(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if kind != tt.wantKind || name != tt.wantName {
+				t.Errorf("parseTarget() = %q, %q, want %q, %q", kind, name, tt.wantKind, tt.wantName)
+			}
+		})
+	}
+}
+
+func workloadPod(name string, owner metav1.OwnerReference, labels map[string]string) *v1.Pod {
+	pod := testPod()
+	pod.Name = name
+	pod.Labels = labels
+	pod.OwnerReferences = []metav1.OwnerReference{owner}
+	return pod
+}
+
+func TestFindWorkload(t *testing.T) {
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "nginx"}}},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: objectMeta("web", nil),
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}, Template: template},
+		Status:     appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 1},
+	}
+	elsewhere := deploy.DeepCopy()
+	elsewhere.Namespace = "staging"
+	rs := metav1.OwnerReference{Kind: "ReplicaSet", Name: "web-7d4b9"}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: objectMeta("backup", nil),
+		Spec:       batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}}},
+	}
+	job := &batchv1.Job{ObjectMeta: objectMeta("backup-1", nil)}
+	job.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "backup"}}
+	objects := []runtime.Object{
+		deploy, elsewhere, cronJob, job,
+		workloadPod("web-b", rs, map[string]string{"app": "web"}),
+		workloadPod("web-a", rs, map[string]string{"app": "web"}),
+		workloadPod("db-0", metav1.OwnerReference{Kind: "StatefulSet", Name: "db"}, map[string]string{"app": "db"}),
+		workloadPod("backup-1-xyz", metav1.OwnerReference{Kind: "Job", Name: "backup-1"}, nil),
+	}
+
+	tests := []struct {
+		name         string
+		kind         string
+		target       string
+		namespace    string
+		wantWorkload Workload
+		wantPods     []string
+		wantErr      bool
+	}{
+		{name: "deployment", kind: "Deployment", target: "web", namespace: testNamespace,
+			wantWorkload: Workload{Type: "Deployment", Name: "web", Replicas: "1/2", Status: true},
+			wantPods:     []string{"web-a", "web-b"}},
+		{name: "cron job", kind: "CronJob", target: "backup", namespace: testNamespace,
+			wantWorkload: Workload{Type: "CronJob", Name: "backup", Replicas: "0 active"},
+			wantPods:     []string{"backup-1-xyz"}},
+		{name: "not found", kind: "StatefulSet", target: "web", namespace: testNamespace, wantErr: true},
+		{name: "ambiguous across namespaces", kind: "Deployment", target: "web", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, _ := newTestPlugin(objects...)
+			err := sf.findWorkload(context.Background(), tt.kind, tt.target, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findWorkload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if sf.AllInfo.Workload != tt.wantWorkload {
+				t.Errorf("Workload = %+v, want %+v", sf.AllInfo.Workload, tt.wantWorkload)
+			}
+			var pods []string
+			for _, p := range sf.AllInfo.Pods {
+				pods = append(pods, p.Name)
+			}
+			if !reflect.DeepEqual(pods, tt.wantPods) {
+				t.Errorf("Pods = %v, want %v", pods, tt.wantPods)
+			}
+			if sf.PodObject.Name != tt.target || !reflect.DeepEqual(sf.PodObject.Labels, template.Labels) ||
+				sf.PodObject.Spec.Containers[0].Image != "nginx" {
+				t.Errorf("template pod = %+v", sf.PodObject)
+			}
+		})
+	}
+}
+
+func TestSummarizePod(t *testing.T) {
+	running := testPod()
+	running.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "app", Ready: true, RestartCount: 1}}
+	crashing := testPod()
+	crashing.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "app", RestartCount: 7,
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}}
+	notReady := testPod()
+	notReady.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "app"}}
+	pending := testPod()
+	pending.Spec.NodeName = ""
+	pending.Status = v1.PodStatus{Phase: v1.PodPending}
+
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want PodSummary
+	}{
+		{name: "running", pod: running,
+			want: PodSummary{Name: running.Name, State: "Running", Ready: "1/1", Restarts: 1, Node: "node-1"}},
+		{name: "crash loop", pod: crashing,
+			want: PodSummary{Name: crashing.Name, State: "CrashLoopBackOff", Ready: "0/1", Restarts: 7, Node: "node-1", Unhealthy: true}},
+		{name: "not ready", pod: notReady,
+			want: PodSummary{Name: notReady.Name, State: "Running", Ready: "0/1", Node: "node-1", Unhealthy: true}},
+		{name: "pending", pod: pending,
+			want: PodSummary{Name: pending.Name, State: "Pending", Ready: "0/1", Unhealthy: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizePod(tt.pod); got != tt.want {
+				t.Errorf("summarizePod() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := podStateCounts([]PodSummary{{State: "Running"}, {State: "CrashLoopBackOff"}, {State: "Running"}}); got != "1 CrashLoopBackOff, 2 Running" {
+		t.Errorf("podStateCounts() = %q", got)
+	}
+}
+
+func TestFindRelatedFromWorkload(t *testing.T) {
+	objects := append(testCluster(), &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "e1", Namespace: testNamespace},
+		InvolvedObject: v1.ObjectReference{Kind: "Deployment", Name: "web", Namespace: testNamespace},
+		Reason:         "ScalingReplicaSet",
+	})
+	deploy := objects[3].(*appsv1.Deployment)
+	deploy.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deploy.Spec.Template = v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec:       testPod().Spec,
+	}
+	sf, _ := newTestPlugin(objects...)
+	ctx := context.Background()
+	if err := sf.findWorkload(ctx, "Deployment", "web", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.getLabelByPod("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.findRelated(ctx, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if sf.AllInfo.Node != nil || sf.AllInfo.Scheduling != nil {
+		t.Error("the template pod should not be looked up on a node or analyzed for scheduling")
+	}
+	if sf.AllInfo.SvcList == nil || len(sf.AllInfo.SvcList.Items) != 1 {
+		t.Errorf("services = %v, want 1", sf.AllInfo.SvcList)
+	}
+	if len(sf.AllInfo.Events) != 1 || sf.AllInfo.Events[0].Reason != "ScalingReplicaSet" {
+		t.Errorf("events = %+v", sf.AllInfo.Events)
+	}
+	doc := sf.buildDocument()
+	if len(doc.Pods) != 1 || doc.Pods[0].Name != "web-7d4b9-abcde" {
+		t.Errorf("document pods = %+v", doc.Pods)
+	}
+	if doc.Pod != nil || doc.PodTemplate == nil || doc.PodTemplate.Spec.Containers[0].Image != "nginx" {
+		t.Errorf("document pod = %v, podTemplate = %v", doc.Pod, doc.PodTemplate)
+	}
+}